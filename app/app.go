@@ -0,0 +1,144 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/mangohow/mangokit/proc"
+	"github.com/sirupsen/logrus"
+)
+
+// App 管理多个Server的生命周期
+// 所有Server并发启动, 当context被取消或者任意一个Server启动失败时, 按照相反的顺序停止所有Server
+type App struct {
+	opts options
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	stopped bool
+}
+
+func New(opts ...Option) *App {
+	o := options{
+		stopTimeout: defaultStopTimeout,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.log == nil {
+		o.log = logrus.StandardLogger()
+	}
+
+	return &App{opts: o}
+}
+
+// Run 启动所有Server并阻塞, 直到所有Server停止, 返回启动, 停止以及钩子函数中产生的所有错误
+func (a *App) Run() error {
+	if a.opts.ctx == nil {
+		a.opts.ctx = proc.SetupSignalHandler()
+	}
+	ctx, cancel := context.WithCancel(a.opts.ctx)
+	a.mu.Lock()
+	if a.stopped {
+		a.mu.Unlock()
+		cancel()
+		return nil
+	}
+	a.cancel = cancel
+	a.mu.Unlock()
+	defer cancel()
+
+	for _, fn := range a.opts.beforeStart {
+		if err := fn(ctx); err != nil {
+			return err
+		}
+	}
+
+	var (
+		wg      sync.WaitGroup
+		errMu   sync.Mutex
+		runErrs []error
+	)
+	addErr := func(err error) {
+		errMu.Lock()
+		runErrs = append(runErrs, err)
+		errMu.Unlock()
+	}
+
+	// Server使用单独的context启动, 信号到来时不会直接取消, 而是由Stop按照相反的顺序停止
+	runCtx, runCancel := context.WithCancel(context.Background())
+	defer runCancel()
+	for _, srv := range a.opts.servers {
+		srv := srv
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := srv.Start(runCtx); err != nil {
+				a.opts.log.Errorf("app: server start failed, %v", err)
+				addErr(err)
+				cancel()
+			}
+		}()
+	}
+
+	for _, fn := range a.opts.afterStart {
+		if err := fn(ctx); err != nil {
+			addErr(err)
+			cancel()
+			break
+		}
+	}
+
+	<-ctx.Done()
+	a.opts.log.Info("app: stopping")
+
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), a.opts.stopTimeout)
+	defer stopCancel()
+	for _, fn := range a.opts.beforeStop {
+		if err := fn(stopCtx); err != nil {
+			addErr(err)
+		}
+	}
+
+	for i := len(a.opts.servers) - 1; i >= 0; i-- {
+		if err := a.opts.servers[i].Stop(stopCtx); err != nil {
+			a.opts.log.Errorf("app: server stop failed, %v", err)
+			addErr(err)
+		}
+	}
+
+	// 等待所有Server的Start返回
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-stopCtx.Done():
+		addErr(stopCtx.Err())
+	}
+
+	for _, fn := range a.opts.afterStop {
+		if err := fn(stopCtx); err != nil {
+			addErr(err)
+		}
+	}
+
+	errMu.Lock()
+	defer errMu.Unlock()
+
+	return errors.Join(runErrs...)
+}
+
+// Stop 主动停止App, Run会在所有Server停止后返回, 在Run之前调用时Run不会启动任何Server, 直接返回
+func (a *App) Stop() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stopped = true
+	if a.cancel != nil {
+		a.cancel()
+	}
+}
@@ -0,0 +1,159 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeServer struct {
+	name     string
+	startErr error
+	stopped  chan struct{}
+	once     sync.Once
+	record   func(string)
+}
+
+func newFakeServer(name string, record func(string)) *fakeServer {
+	return &fakeServer{name: name, stopped: make(chan struct{}), record: record}
+}
+
+func (s *fakeServer) Start(ctx context.Context) error {
+	if s.startErr != nil {
+		return s.startErr
+	}
+	<-s.stopped
+	return nil
+}
+
+func (s *fakeServer) Stop(ctx context.Context) error {
+	s.record("stop " + s.name)
+	s.once.Do(func() { close(s.stopped) })
+	return nil
+}
+
+func TestAppStopOrder(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		trace []string
+	)
+	record := func(s string) {
+		mu.Lock()
+		trace = append(trace, s)
+		mu.Unlock()
+	}
+	hook := func(name string) Hook {
+		return func(ctx context.Context) error {
+			record(name)
+			return nil
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a := New(WithContext(ctx),
+		WithServer(newFakeServer("a", record), newFakeServer("b", record)),
+		WithServer(Worker(func(ctx context.Context) error {
+			<-ctx.Done()
+			record("worker done")
+			return ctx.Err()
+		})),
+		BeforeStart(hook("before start")),
+		AfterStart(hook("after start")),
+		BeforeStop(hook("before stop")),
+		AfterStop(hook("after stop")))
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if err := a.Run(); err != nil {
+		t.Fatalf("run error %v", err)
+	}
+
+	expect := []string{"before start", "after start", "before stop", "worker done", "stop b", "stop a", "after stop"}
+	if !reflect.DeepEqual(trace, expect) {
+		t.Errorf("trace = %v, want %v", trace, expect)
+	}
+}
+
+func TestAppStartError(t *testing.T) {
+	startErr := errors.New("listen failed")
+	failed := newFakeServer("failed", func(string) {})
+	failed.startErr = startErr
+	stopErr := errors.New("stop timeout")
+
+	a := New(WithContext(context.Background()),
+		WithServer(newFakeServer("ok", func(string) {}), failed),
+		AfterStop(func(ctx context.Context) error { return stopErr }))
+
+	err := a.Run()
+	if !errors.Is(err, startErr) || !errors.Is(err, stopErr) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestWorkerStopBeforeStart(t *testing.T) {
+	w := Worker(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err := w.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- w.Start(context.Background()) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("worker started after stop")
+	}
+}
+
+func TestAppStopBeforeRun(t *testing.T) {
+	var started atomic.Bool
+	a := New(WithContext(context.Background()), WithServer(Worker(func(ctx context.Context) error {
+		started.Store(true)
+		<-ctx.Done()
+		return fmt.Errorf("worker: %w", ctx.Err())
+	})))
+	a.Stop()
+
+	done := make(chan error, 1)
+	go func() { done <- a.Run() }()
+	select {
+	case err := <-done:
+		if err != nil || started.Load() {
+			t.Fatalf("unexpected run: started=%v err=%v", started.Load(), err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("app run after stop")
+	}
+}
+
+func TestWorkerWrappedCanceled(t *testing.T) {
+	started := make(chan struct{})
+	w := Worker(func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return fmt.Errorf("worker: %w", ctx.Err())
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- w.Start(context.Background()) }()
+	<-started
+	if err := w.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const defaultStopTimeout = 10 * time.Second
+
+// Hook 生命周期钩子函数
+type Hook func(ctx context.Context) error
+
+type options struct {
+	ctx         context.Context
+	servers     []Server
+	stopTimeout time.Duration
+	log         *logrus.Logger
+
+	beforeStart []Hook
+	afterStart  []Hook
+	beforeStop  []Hook
+	afterStop   []Hook
+}
+
+type Option func(o *options)
+
+// WithContext 设置App的context, context取消时App开始停止, 默认使用proc.SetupSignalHandler
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// WithServer 添加由App管理的Server, 按照添加的相反顺序停止
+func WithServer(servers ...Server) Option {
+	return func(o *options) {
+		o.servers = append(o.servers, servers...)
+	}
+}
+
+// WithStopTimeout 设置停止所有Server的超时时间, 默认为10s
+func WithStopTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.stopTimeout = timeout
+	}
+}
+
+func WithLogger(log *logrus.Logger) Option {
+	return func(o *options) {
+		o.log = log
+	}
+}
+
+// BeforeStart 在启动Server之前执行, 返回错误时App不会启动
+func BeforeStart(fn Hook) Option {
+	return func(o *options) {
+		o.beforeStart = append(o.beforeStart, fn)
+	}
+}
+
+// AfterStart 在所有Server的Start被调用之后执行, 不会等待Server就绪, 返回错误时App会停止
+func AfterStart(fn Hook) Option {
+	return func(o *options) {
+		o.afterStart = append(o.afterStart, fn)
+	}
+}
+
+// BeforeStop 在停止Server之前执行
+func BeforeStop(fn Hook) Option {
+	return func(o *options) {
+		o.beforeStop = append(o.beforeStop, fn)
+	}
+}
+
+// AfterStop 在所有Server停止之后执行
+func AfterStop(fn Hook) Option {
+	return func(o *options) {
+		o.afterStop = append(o.afterStop, fn)
+	}
+}
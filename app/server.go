@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Server 由App管理生命周期的组件, Start会阻塞直到Server停止
+type Server interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+// StartStopper Start不接收context的组件, 例如transport/http.Server
+type StartStopper interface {
+	Start() error
+	Stop(ctx context.Context) error
+}
+
+type wrappedServer struct {
+	s StartStopper
+}
+
+func (w wrappedServer) Start(ctx context.Context) error {
+	return w.s.Start()
+}
+
+func (w wrappedServer) Stop(ctx context.Context) error {
+	return w.s.Stop(ctx)
+}
+
+// Wrap 将StartStopper转换为Server, 例如 app.Wrap(httpServer)
+func Wrap(s StartStopper) Server {
+	return wrappedServer{s: s}
+}
+
+// worker 后台任务, Stop时取消context并等待任务退出
+// Stop在Start之前调用时, 之后的Start不会再执行任务
+type worker struct {
+	fn func(ctx context.Context) error
+
+	mu      sync.Mutex
+	stopped bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// Worker 将后台任务转换为Server, fn需要在ctx取消后退出
+func Worker(fn func(ctx context.Context) error) Server {
+	return &worker{fn: fn}
+}
+
+func (w *worker) Start(ctx context.Context) error {
+	w.mu.Lock()
+	if w.stopped {
+		w.mu.Unlock()
+		return nil
+	}
+	ctx, w.cancel = context.WithCancel(ctx)
+	w.done = make(chan struct{})
+	w.mu.Unlock()
+
+	defer close(w.done)
+	err := w.fn(ctx)
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+
+	return err
+}
+
+func (w *worker) Stop(ctx context.Context) error {
+	w.mu.Lock()
+	w.stopped = true
+	cancel, done := w.cancel, w.done
+	w.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+
+	cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
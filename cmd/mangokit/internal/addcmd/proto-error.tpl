@@ -8,6 +8,8 @@ option go_package = "{{.Package}};{{.DirName}}";
 
 enum {{.Name}} {
 	option (errors.default_code) = 500;
+	// 业务错误码范围, 设置后错误码为start加上枚举值, 也可以使用 [(errors.biz_code) = 10001] 为错误单独设置
+	// option (errors.enum_code_range) = {start: 10000, end: 10999};
 
 	Placeholder = 0 [(errors.code) = 0];
 
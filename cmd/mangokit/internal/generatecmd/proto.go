@@ -29,13 +29,17 @@ var CmdGenProto = &cobra.Command{
 
 var (
 	protoPath = []string{"third_party", "."}
+	validate  bool
+	catalog   string
 )
 
 func init() {
 	CmdGenProto.Flags().StringSliceVarP(&protoPath, "proto_path", "p", protoPath, "specify proto_path")
+	CmdGenProto.Flags().BoolVar(&validate, "validate", validate, "generate Validate methods with protoc-gen-validate, protoc-gen-validate must be installed")
+	CmdGenProto.Flags().StringVar(&catalog, "error_catalog", catalog, "generate error catalog with protoc-gen-go-error: json, markdown or all")
 }
 
-//  protoc --proto_path=third_party --proto_path=api --gogo_out=. --go-gin_out=. --go-error_out=. api/mangokit/v1/proto/mangokit.proto api/helloworld/v1/proto/greeter.proto
+//  protoc --proto_path=third_party --proto_path=api --gogo_out=. --go-gin_out=. --go-error_out=. --validate_out=lang=go:. api/mangokit/v1/proto/mangokit.proto api/helloworld/v1/proto/greeter.proto
 
 func GenerateProtos(dir string) error {
 	// 遍历目录, 获取所有proto文件
@@ -68,6 +72,12 @@ func GenerateProtos(dir string) error {
 	args = append(args, "--go_out=.")
 	args = append(args, "--go-gin_out=.")
 	args = append(args, "--go-error_out=.")
+	if catalog != "" {
+		args = append(args, "--go-error_opt=catalog="+catalog)
+	}
+	if validate {
+		args = append(args, "--validate_out=lang=go:.")
+	}
 	args = append(args, protos...)
 
 	cmd := exec.Command("protoc", args...)
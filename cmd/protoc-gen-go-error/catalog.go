@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+const (
+	catalogJSON     = "json"
+	catalogMarkdown = "markdown"
+	catalogAll      = "all"
+)
+
+type catalogEntry struct {
+	Code       int32  `json:"code"`
+	Reason     string `json:"reason"`
+	HTTPStatus int    `json:"http_status"`
+	Desc       string `json:"desc,omitempty"`
+	Comment    string `json:"comment,omitempty"`
+	Enum       string `json:"enum"`
+	Source     string `json:"source"`
+}
+
+// errorCatalog 一个go package中定义的所有错误, 按照错误码排序
+type errorCatalog struct {
+	Package string         `json:"package"`
+	Errors  []catalogEntry `json:"errors"`
+
+	dir    string
+	goPath protogen.GoImportPath
+}
+
+// buildCatalogs 将错误按照go package分组, 同一个package中由biz_code或者code_range指定的错误码重复时返回错误, 错误码为0的错误会被忽略
+func buildCatalogs(files []*protogen.File, descs map[*protogen.File][]*ErrorDesc) ([]*errorCatalog, error) {
+	var (
+		catalogs []*errorCatalog
+		byPath   = make(map[protogen.GoImportPath]*errorCatalog)
+		codes    = make(map[protogen.GoImportPath]map[int32]*ErrorDesc)
+	)
+	for _, file := range files {
+		if len(descs[file]) == 0 {
+			continue
+		}
+		c, ok := byPath[file.GoImportPath]
+		if !ok {
+			c = &errorCatalog{
+				Package: string(file.Desc.Package()),
+				dir:     path.Dir(file.GeneratedFilenamePrefix),
+				goPath:  file.GoImportPath,
+			}
+			byPath[file.GoImportPath] = c
+			codes[file.GoImportPath] = make(map[int32]*ErrorDesc)
+			catalogs = append(catalogs, c)
+		}
+
+		for _, d := range descs[file] {
+			// 错误码0保留给成功, 例如没有设置错误码范围时的Placeholder = 0, 不参与重复检查, 也不生成到错误目录中
+			if d.Code == 0 {
+				continue
+			}
+			// 直接使用枚举值作为错误码时, 多个枚举都从1开始编号是常见的用法, 与之前的版本一致不做检查
+			// 使用biz_code或者code_range指定错误码之后才要求错误码在package中唯一
+			prev, ok := codes[file.GoImportPath][d.Code]
+			if ok && (prev.Assigned || d.Assigned) {
+				return nil, fmt.Errorf("duplicate error code %d in package %s: %s.%s (%s) and %s.%s (%s), "+
+					"codes set by biz_code or code_range must be unique in a package",
+					d.Code, file.GoImportPath, prev.FullName, prev.Name, prev.Source, d.FullName, d.Name, d.Source)
+			}
+			if !ok {
+				codes[file.GoImportPath][d.Code] = d
+			}
+			c.Errors = append(c.Errors, catalogEntry{
+				Code:       d.Code,
+				Reason:     d.Name,
+				HTTPStatus: d.HTTPStatus,
+				Desc:       d.Desc,
+				Comment:    cleanComment(d.Comment),
+				Enum:       d.FullName,
+				Source:     d.Source,
+			})
+		}
+	}
+
+	for _, c := range catalogs {
+		sort.Slice(c.Errors, func(i, j int) bool {
+			return c.Errors[i].Code < c.Errors[j].Code
+		})
+	}
+
+	return catalogs, nil
+}
+
+// generateCatalog 在生成代码的目录下生成errors.catalog.json和errors.catalog.md
+func generateCatalog(gen *protogen.Plugin, c *errorCatalog, format string) error {
+	if format == catalogJSON || format == catalogAll {
+		data, err := json.MarshalIndent(c, "", "  ")
+		if err != nil {
+			return err
+		}
+		g := gen.NewGeneratedFile(path.Join(c.dir, "errors.catalog.json"), c.goPath)
+		g.P(string(data))
+	}
+
+	if format == catalogMarkdown || format == catalogAll {
+		g := gen.NewGeneratedFile(path.Join(c.dir, "errors.catalog.md"), c.goPath)
+		g.P("<!-- Code generated by protoc-gen-go-error. DO NOT EDIT. -->")
+		g.P()
+		g.P("# ", c.Package, " errors")
+		g.P()
+		g.P("| Code | Reason | HTTP Status | Description | Comment | Enum |")
+		g.P("| ---- | ------ | ----------- | ----------- | ------- | ---- |")
+		for _, e := range c.Errors {
+			g.P(fmt.Sprintf("| %d | %s | %d | %s | %s | %s |",
+				e.Code, e.Reason, e.HTTPStatus, escapeCell(e.Desc), escapeCell(e.Comment), e.Enum))
+		}
+	}
+
+	return nil
+}
+
+func validCatalogFormat(format string) bool {
+	switch format {
+	case "", catalogJSON, catalogMarkdown, catalogAll:
+		return true
+	}
+	return false
+}
+
+// cleanComment 去掉注释中的 // 并合并为一行
+func cleanComment(comment string) string {
+	lines := strings.Split(strings.TrimSpace(comment), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "//"))
+	}
+	return strings.TrimSpace(strings.Join(lines, " "))
+}
+
+func escapeCell(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mangohow/mangokit/errors"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+type valueOption func(o *descriptorpb.EnumValueOptions)
+
+func withCode(code int32) valueOption {
+	return func(o *descriptorpb.EnumValueOptions) {
+		proto.SetExtension(o, errors.E_Code, code)
+	}
+}
+
+func withDesc(desc string) valueOption {
+	return func(o *descriptorpb.EnumValueOptions) {
+		proto.SetExtension(o, errors.E_Desc, desc)
+	}
+}
+
+func enumValue(name string, number int32, opts ...valueOption) *descriptorpb.EnumValueDescriptorProto {
+	o := &descriptorpb.EnumValueOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &descriptorpb.EnumValueDescriptorProto{Name: proto.String(name), Number: proto.Int32(number), Options: o}
+}
+
+func enumType(name string, defaultCode int32, values ...*descriptorpb.EnumValueDescriptorProto) *descriptorpb.EnumDescriptorProto {
+	o := &descriptorpb.EnumOptions{}
+	if defaultCode != 0 {
+		proto.SetExtension(o, errors.E_DefaultCode, defaultCode)
+	}
+	return &descriptorpb.EnumDescriptorProto{Name: proto.String(name), Value: values, Options: o}
+}
+
+func protoFile(name, goPackage string, enums ...*descriptorpb.EnumDescriptorProto) *descriptorpb.FileDescriptorProto {
+	return &descriptorpb.FileDescriptorProto{
+		Name:       proto.String(name),
+		Package:    proto.String("user.v1"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"errors/errors.proto"},
+		Options:    &descriptorpb.FileOptions{GoPackage: proto.String(goPackage)},
+		EnumType:   enums,
+	}
+}
+
+// runPlugin 使用files构造CodeGeneratorRequest执行插件, 返回生成的文件名和内容
+func runPlugin(t *testing.T, catalog string, files ...*descriptorpb.FileDescriptorProto) (map[string]string, error) {
+	t.Helper()
+
+	errFile := protodesc.ToFileDescriptorProto(errors.File_third_party_errors_errors_proto)
+	errFile.Name = proto.String("errors/errors.proto")
+	req := &pluginpb.CodeGeneratorRequest{
+		Parameter: proto.String("paths=source_relative"),
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto), errFile,
+		},
+	}
+	for _, f := range files {
+		req.FileToGenerate = append(req.FileToGenerate, f.GetName())
+		req.ProtoFile = append(req.ProtoFile, f)
+	}
+
+	gen, err := protogen.Options{}.New(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := generate(gen, catalog); err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]string)
+	for _, f := range gen.Response().GetFile() {
+		out[f.GetName()] = f.GetContent()
+	}
+	return out, nil
+}
+
+func TestGenerateErrors(t *testing.T) {
+	const pkg = "example.com/user/v1;v1"
+
+	tests := []struct {
+		name     string
+		files    []*descriptorpb.FileDescriptorProto
+		contains map[string][]string
+		missing  map[string][]string
+		err      string
+	}{
+		{
+			name: "explicit code 0 falls back to default_code",
+			files: []*descriptorpb.FileDescriptorProto{protoFile("user/v1/user.proto", pkg,
+				enumType("UserError", 500,
+					enumValue("Placeholder", 0, withCode(0)),
+					enumValue("USER_NOT_FOUND", 1, withCode(404), withDesc("user not found"))))},
+			contains: map[string][]string{"user/v1/user_errors.pb.go": {
+				"func NewErrorPlaceholder(", "errors.New(0, 500, UserError_Placeholder.String()",
+				"errors.New(1, 404, UserError_USER_NOT_FOUND.String()",
+				"ErrorUSER_NOT_FOUND = errors.New(1, 404, \"USER_NOT_FOUND\", Desc_USER_NOT_FOUND)",
+				"errors.Register(\n\t\tErrorUSER_NOT_FOUND,\n\t)",
+			}},
+			// 错误码0不会注册, 有描述的错误注册共享的错误变量
+			missing: map[string][]string{"user/v1/user_errors.pb.go": {"errors.New(0, 500, \"Placeholder\""}},
+		},
+		{
+			name: "values without code and default_code are skipped",
+			files: []*descriptorpb.FileDescriptorProto{protoFile("user/v1/user.proto", pkg,
+				enumType("UserError", 0,
+					enumValue("UNKNOWN", 0),
+					enumValue("USER_EXISTS", 2, withCode(409))))},
+			contains: map[string][]string{"user/v1/user_errors.pb.go": {
+				"func NewErrorUserExists(", "errors.New(2, 409, \"USER_EXISTS\", \"\"),",
+			}},
+			missing: map[string][]string{"user/v1/user_errors.pb.go": {"NewErrorUnknown"}},
+		},
+		{
+			name: "enum without errors is skipped",
+			files: []*descriptorpb.FileDescriptorProto{protoFile("user/v1/user.proto", pkg,
+				enumType("Status", 0, enumValue("OK", 0)))},
+			missing: map[string][]string{"user/v1/user_errors.pb.go": {""}},
+		},
+		{
+			name: "code out of range",
+			files: []*descriptorpb.FileDescriptorProto{protoFile("user/v1/user.proto", pkg,
+				enumType("UserError", 0, enumValue("UNKNOWN", 0), enumValue("USER_EXISTS", 1, withCode(700))))},
+			err: "user.v1.UserError.USER_EXISTS: code 700 must be greater than 0",
+		},
+		{
+			name: "bad default_code",
+			files: []*descriptorpb.FileDescriptorProto{protoFile("user/v1/user.proto", pkg,
+				enumType("UserError", 601, enumValue("UNKNOWN", 0)))},
+			err: "user.v1.UserError: default_code 601 must be greater than 0",
+		},
+		{
+			name: "duplicate enum numbers in the same package",
+			files: []*descriptorpb.FileDescriptorProto{
+				protoFile("user/v1/user.proto", pkg, enumType("UserError", 500,
+					enumValue("USER_PLACEHOLDER", 0, withCode(0)), enumValue("USER_EXISTS", 1))),
+				protoFile("user/v1/account.proto", pkg, enumType("AccountError", 500,
+					enumValue("ACCOUNT_PLACEHOLDER", 0, withCode(0)), enumValue("ACCOUNT_LOCKED", 1))),
+			},
+			contains: map[string][]string{
+				"user/v1/user_errors.pb.go":    {"NewErrorUserExists"},
+				"user/v1/account_errors.pb.go": {"NewErrorAccountLocked"},
+			},
+		},
+		{
+			name: "duplicate biz_code in the same package",
+			files: []*descriptorpb.FileDescriptorProto{
+				protoFile("user/v1/user.proto", pkg, enumType("UserError", 500,
+					enumValue("USER_PLACEHOLDER", 0, withCode(0)), enumValue("USER_EXISTS", 1))),
+				protoFile("user/v1/account.proto", pkg, enumType("AccountError", 500,
+					enumValue("ACCOUNT_PLACEHOLDER", 0, withCode(0)), enumValue("ACCOUNT_LOCKED", 2, withBizCode(1)))),
+			},
+			err: `duplicate error code 1 in package "example.com/user/v1": user.v1.UserError.USER_EXISTS (user/v1/user.proto) and user.v1.AccountError.ACCOUNT_LOCKED (user/v1/account.proto)`,
+		},
+		{
+			name: "one init per file",
+			files: []*descriptorpb.FileDescriptorProto{protoFile("user/v1/user.proto", pkg,
+				enumType("UserError", 500, enumValue("USER_PLACEHOLDER", 0, withCode(0)), enumValue("USER_EXISTS", 1)),
+				enumType("AccountError", 500, enumValue("ACCOUNT_PLACEHOLDER", 0, withCode(0)), enumValue("ACCOUNT_LOCKED", 2)))},
+			contains: map[string][]string{"user/v1/user_errors.pb.go": {
+				"errors.Register(\n\t\terrors.New(1, 500, \"USER_EXISTS\", \"\"),\n\t\terrors.New(2, 500, \"ACCOUNT_LOCKED\", \"\"),\n\t)",
+			}},
+		},
+		{
+			name: "same codes in different packages",
+			files: []*descriptorpb.FileDescriptorProto{
+				protoFile("user/v1/user.proto", pkg, enumType("UserError", 500,
+					enumValue("USER_UNKNOWN", 0), enumValue("USER_EXISTS", 1))),
+				protoFile("account/v1/account.proto", "example.com/account/v1;v1", enumType("AccountError", 500,
+					enumValue("ACCOUNT_UNKNOWN", 0), enumValue("ACCOUNT_LOCKED", 1))),
+			},
+			contains: map[string][]string{
+				"user/v1/user_errors.pb.go":       {"NewErrorUserExists"},
+				"account/v1/account_errors.pb.go": {"NewErrorAccountLocked"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := runPlugin(t, "", tt.files...)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expect error %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, subs := range tt.contains {
+				for _, sub := range subs {
+					if !strings.Contains(out[name], sub) {
+						t.Errorf("%s does not contain %q\n%s", name, sub, out[name])
+					}
+				}
+			}
+			for name, subs := range tt.missing {
+				content, ok := out[name]
+				for _, sub := range subs {
+					if ok && (sub == "" || strings.Contains(content, sub)) {
+						t.Errorf("%s should not contain %q\n%s", name, sub, content)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateCatalog(t *testing.T) {
+	files := []*descriptorpb.FileDescriptorProto{
+		protoFile("user/v1/user.proto", "example.com/user/v1;v1", enumType("UserError", 500,
+			enumValue("Placeholder", 0, withCode(0)),
+			enumValue("USER_EXISTS", 3, withCode(409), withDesc("user | exists")),
+			enumValue("USER_NOT_FOUND", 1, withCode(404)))),
+		protoFile("user/v1/account.proto", "example.com/user/v1;v1", enumType("AccountError", 500,
+			enumValue("ACCOUNT_UNKNOWN", 0, withCode(0)),
+			enumValue("ACCOUNT_LOCKED", 2, withCode(403)))),
+	}
+
+	tests := []struct {
+		catalog string
+		files   []string
+		err     string
+	}{
+		{catalog: "", files: nil},
+		{catalog: catalogJSON, files: []string{"user/v1/errors.catalog.json"}},
+		{catalog: catalogMarkdown, files: []string{"user/v1/errors.catalog.md"}},
+		{catalog: catalogAll, files: []string{"user/v1/errors.catalog.json", "user/v1/errors.catalog.md"}},
+		{catalog: "yaml", err: `invalid catalog format "yaml"`},
+	}
+	for _, tt := range tests {
+		out, err := runPlugin(t, tt.catalog, files...)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: expect error %q, got %v", tt.catalog, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var catalogs []string
+		for name := range out {
+			if strings.Contains(name, "errors.catalog") {
+				catalogs = append(catalogs, name)
+			}
+		}
+		if len(catalogs) != len(tt.files) {
+			t.Errorf("%s: unexpected catalogs %v", tt.catalog, catalogs)
+		}
+		for _, name := range tt.files {
+			if _, ok := out[name]; !ok {
+				t.Errorf("%s: %s not generated", tt.catalog, name)
+			}
+		}
+	}
+
+	out, err := runPlugin(t, catalogAll, files...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var c errorCatalog
+	if err := json.Unmarshal([]byte(out["user/v1/errors.catalog.json"]), &c); err != nil {
+		t.Fatal(err)
+	}
+	// 按照错误码排序, 错误码0被忽略
+	expect := []catalogEntry{
+		{Code: 1, Reason: "USER_NOT_FOUND", HTTPStatus: 404, Enum: "user.v1.UserError", Source: "user/v1/user.proto"},
+		{Code: 2, Reason: "ACCOUNT_LOCKED", HTTPStatus: 403, Enum: "user.v1.AccountError", Source: "user/v1/account.proto"},
+		{Code: 3, Reason: "USER_EXISTS", HTTPStatus: 409, Desc: "user | exists", Enum: "user.v1.UserError", Source: "user/v1/user.proto"},
+	}
+	if c.Package != "user.v1" || len(c.Errors) != len(expect) {
+		t.Fatalf("unexpected catalog %+v", c)
+	}
+	for i := range expect {
+		if c.Errors[i] != expect[i] {
+			t.Errorf("entry %d: expect %+v, got %+v", i, expect[i], c.Errors[i])
+		}
+	}
+
+	md := out["user/v1/errors.catalog.md"]
+	for _, line := range []string{
+		"# user.v1 errors",
+		"| 1 | USER_NOT_FOUND | 404 |  |  | user.v1.UserError |",
+		`| 3 | USER_EXISTS | 409 | user \| exists |  | user.v1.UserError |`,
+	} {
+		if !strings.Contains(md, line) {
+			t.Errorf("markdown catalog does not contain %q\n%s", line, md)
+		}
+	}
+}
+
+func TestCleanComment(t *testing.T) {
+	if got := cleanComment("// user not found\n// second line\n"); got != "user not found second line" {
+		t.Errorf("unexpected comment %q", got)
+	}
+}
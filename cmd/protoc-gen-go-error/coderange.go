@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mangohow/mangokit/errors"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// codeRange 业务错误码范围, 包含start和end
+type codeRange struct {
+	start, end int32
+	owner      string // 定义范围的文件或者枚举, 用于错误信息
+}
+
+func (r *codeRange) contains(code int32) bool {
+	return code >= r.start && code <= r.end
+}
+
+func (r *codeRange) overlaps(o *codeRange) bool {
+	return r.start <= o.end && o.start <= r.end
+}
+
+func (r *codeRange) String() string {
+	return fmt.Sprintf("[%d, %d] of %s", r.start, r.end, r.owner)
+}
+
+// getCodeRange 获取选项中的错误码范围, 未设置时返回nil
+// 0和负数保留给成功以及errors.UnknownCode, 不能作为业务错误码
+func getCodeRange(opts protoreflect.ProtoMessage, xt protoreflect.ExtensionType, owner string) (*codeRange, error) {
+	if !proto.HasExtension(opts, xt) {
+		return nil, nil
+	}
+	m, _ := proto.GetExtension(opts, xt).(*errors.CodeRange)
+	r := &codeRange{start: m.GetStart(), end: m.GetEnd(), owner: owner}
+	if r.start <= 0 || r.end < r.start {
+		return nil, fmt.Errorf("%s: invalid code range [%d, %d], start must be greater than 0 and end must not be less than start",
+			owner, r.start, r.end)
+	}
+
+	return r, nil
+}
+
+// enumCodeRange 获取枚举使用的错误码范围, 枚举的范围必须位于文件的范围之内, 枚举没有设置时使用文件的范围
+func enumCodeRange(file *protogen.File, enum *protogen.Enum) (*codeRange, error) {
+	fileRange, err := getCodeRange(file.Desc.Options(), errors.E_CodeRange, file.Desc.Path())
+	if err != nil {
+		return nil, err
+	}
+	enumRange, err := getCodeRange(enum.Desc.Options(), errors.E_EnumCodeRange, string(enum.Desc.FullName()))
+	if err != nil {
+		return nil, err
+	}
+	if enumRange == nil {
+		return fileRange, nil
+	}
+	if fileRange != nil && (!fileRange.contains(enumRange.start) || !fileRange.contains(enumRange.end)) {
+		return nil, fmt.Errorf("code range %s is out of %s", enumRange, fileRange)
+	}
+
+	return enumRange, nil
+}
+
+// bizCode 计算枚举值的业务错误码
+// 优先使用mangokit.biz_code, 其次使用错误码范围的start加上枚举值, 都没有时与之前的版本一致使用枚举值
+func bizCode(value *protogen.EnumValue, r *codeRange) (int32, error) {
+	name := fmt.Sprintf("%s.%s", value.Desc.Parent().FullName(), value.Desc.Name())
+	code := int32(value.Desc.Number())
+	explicit := proto.HasExtension(value.Desc.Options(), errors.E_BizCode)
+	switch {
+	case explicit:
+		code = proto.GetExtension(value.Desc.Options(), errors.E_BizCode).(int32)
+	case r != nil:
+		code = r.start + code
+	}
+
+	if (explicit || r != nil) && code <= 0 {
+		return 0, fmt.Errorf("%s: business code %d is reserved, must be greater than 0", name, code)
+	}
+	if r != nil && !r.contains(code) {
+		return 0, fmt.Errorf("%s: business code %d is out of %s", name, code, r)
+	}
+
+	return code, nil
+}
+
+// checkCodeRanges 检查同一个package中的错误码范围是否重叠, 多个文件可以使用相同的文件范围
+func checkCodeRanges(files []*protogen.File) error {
+	type packageRanges struct {
+		files []*codeRange
+		enums []*codeRange
+	}
+	packages := make(map[protogen.GoImportPath]*packageRanges)
+	for _, file := range files {
+		p, ok := packages[file.GoImportPath]
+		if !ok {
+			p = &packageRanges{}
+			packages[file.GoImportPath] = p
+		}
+
+		fr, err := getCodeRange(file.Desc.Options(), errors.E_CodeRange, file.Desc.Path())
+		if err != nil {
+			return err
+		}
+		if fr != nil {
+			for _, o := range p.files {
+				if fr.overlaps(o) && (fr.start != o.start || fr.end != o.end) {
+					return fmt.Errorf("code range %s overlaps with %s", fr, o)
+				}
+			}
+			p.files = append(p.files, fr)
+		}
+
+		for _, enum := range file.Enums {
+			er, err := getCodeRange(enum.Desc.Options(), errors.E_EnumCodeRange, string(enum.Desc.FullName()))
+			if err != nil {
+				return err
+			}
+			if er == nil {
+				continue
+			}
+			for _, o := range p.enums {
+				if er.overlaps(o) {
+					return fmt.Errorf("code range %s overlaps with %s", er, o)
+				}
+			}
+			p.enums = append(p.enums, er)
+		}
+	}
+
+	return nil
+}
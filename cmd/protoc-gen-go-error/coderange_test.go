@@ -0,0 +1,156 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mangohow/mangokit/errors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func withBizCode(code int32) valueOption {
+	return func(o *descriptorpb.EnumValueOptions) {
+		proto.SetExtension(o, errors.E_BizCode, code)
+	}
+}
+
+func withEnumRange(e *descriptorpb.EnumDescriptorProto, start, end int32) *descriptorpb.EnumDescriptorProto {
+	proto.SetExtension(e.Options, errors.E_EnumCodeRange, &errors.CodeRange{Start: start, End: end})
+	return e
+}
+
+func withFileRange(f *descriptorpb.FileDescriptorProto, start, end int32) *descriptorpb.FileDescriptorProto {
+	proto.SetExtension(f.Options, errors.E_CodeRange, &errors.CodeRange{Start: start, End: end})
+	return f
+}
+
+func TestCodeRange(t *testing.T) {
+	const pkg = "example.com/user/v1;v1"
+	userFile := func(enums ...*descriptorpb.EnumDescriptorProto) *descriptorpb.FileDescriptorProto {
+		return protoFile("user/v1/user.proto", pkg, enums...)
+	}
+	userError := func(values ...*descriptorpb.EnumValueDescriptorProto) *descriptorpb.EnumDescriptorProto {
+		return enumType("UserError", 500, append([]*descriptorpb.EnumValueDescriptorProto{
+			enumValue("USER_UNKNOWN", 0, withCode(0))}, values...)...)
+	}
+
+	tests := []struct {
+		name     string
+		files    []*descriptorpb.FileDescriptorProto
+		contains []string
+		err      string
+	}{
+		{
+			name:     "enum range",
+			files:    []*descriptorpb.FileDescriptorProto{userFile(withEnumRange(userError(enumValue("USER_EXISTS", 1)), 10000, 10999))},
+			contains: []string{"errors.New(10001, 500, UserError_USER_EXISTS.String()", "errors.New(10000, 500, UserError_USER_UNKNOWN.String()"},
+		},
+		{
+			name:     "file range",
+			files:    []*descriptorpb.FileDescriptorProto{withFileRange(userFile(userError(enumValue("USER_EXISTS", 5))), 20000, 29999)},
+			contains: []string{"errors.New(20005, 500, UserError_USER_EXISTS.String()"},
+		},
+		{
+			name: "biz_code in range",
+			files: []*descriptorpb.FileDescriptorProto{userFile(withEnumRange(userError(
+				enumValue("USER_EXISTS", 1, withBizCode(10500))), 10000, 10999))},
+			contains: []string{"errors.New(10500, 500, UserError_USER_EXISTS.String()"},
+		},
+		{
+			name:     "biz_code without range",
+			files:    []*descriptorpb.FileDescriptorProto{userFile(userError(enumValue("USER_EXISTS", 1, withBizCode(42))))},
+			contains: []string{"errors.New(42, 500, UserError_USER_EXISTS.String()"},
+		},
+		{
+			name:     "enum number without range",
+			files:    []*descriptorpb.FileDescriptorProto{userFile(userError(enumValue("USER_EXISTS", 3)))},
+			contains: []string{"errors.New(3, 500, UserError_USER_EXISTS.String()"},
+		},
+		{
+			name: "biz_code out of range",
+			files: []*descriptorpb.FileDescriptorProto{userFile(withEnumRange(userError(
+				enumValue("USER_EXISTS", 1, withBizCode(20000))), 10000, 10999))},
+			err: "user.v1.UserError.USER_EXISTS: business code 20000 is out of [10000, 10999] of user.v1.UserError",
+		},
+		{
+			name:  "enum number out of range",
+			files: []*descriptorpb.FileDescriptorProto{userFile(withEnumRange(userError(enumValue("USER_EXISTS", 100)), 10000, 10099))},
+			err:   "user.v1.UserError.USER_EXISTS: business code 10100 is out of [10000, 10099]",
+		},
+		{
+			name:  "reserved biz_code",
+			files: []*descriptorpb.FileDescriptorProto{userFile(userError(enumValue("USER_EXISTS", 1, withBizCode(-1))))},
+			err:   "user.v1.UserError.USER_EXISTS: business code -1 is reserved",
+		},
+		{
+			name:  "invalid range",
+			files: []*descriptorpb.FileDescriptorProto{userFile(withEnumRange(userError(enumValue("USER_EXISTS", 1)), 0, 100))},
+			err:   "user.v1.UserError: invalid code range [0, 100]",
+		},
+		{
+			name:  "reversed range",
+			files: []*descriptorpb.FileDescriptorProto{withFileRange(userFile(userError(enumValue("USER_EXISTS", 1))), 200, 100)},
+			err:   "user/v1/user.proto: invalid code range [200, 100]",
+		},
+		{
+			name: "enum range out of file range",
+			files: []*descriptorpb.FileDescriptorProto{withFileRange(userFile(withEnumRange(userError(
+				enumValue("USER_EXISTS", 1)), 30000, 30999)), 20000, 29999)},
+			err: "code range [30000, 30999] of user.v1.UserError is out of [20000, 29999] of user/v1/user.proto",
+		},
+		{
+			name: "overlapping enum ranges",
+			files: []*descriptorpb.FileDescriptorProto{
+				userFile(withEnumRange(userError(enumValue("USER_EXISTS", 1)), 10000, 10999)),
+				protoFile("user/v1/account.proto", pkg, withEnumRange(enumType("AccountError", 500,
+					enumValue("ACCOUNT_UNKNOWN", 0, withCode(0)), enumValue("ACCOUNT_LOCKED", 1)), 10500, 11999)),
+			},
+			err: "code range [10500, 11999] of user.v1.AccountError overlaps with [10000, 10999] of user.v1.UserError",
+		},
+		{
+			name: "overlapping file ranges",
+			files: []*descriptorpb.FileDescriptorProto{
+				withFileRange(userFile(userError(enumValue("USER_EXISTS", 1))), 10000, 10999),
+				withFileRange(protoFile("user/v1/account.proto", pkg, enumType("AccountError", 500,
+					enumValue("ACCOUNT_UNKNOWN", 0, withCode(0)), enumValue("ACCOUNT_LOCKED", 1))), 10900, 11999),
+			},
+			err: "code range [10900, 11999] of user/v1/account.proto overlaps with [10000, 10999] of user/v1/user.proto",
+		},
+		{
+			name: "adjacent enum ranges",
+			files: []*descriptorpb.FileDescriptorProto{
+				userFile(withEnumRange(userError(enumValue("USER_EXISTS", 1)), 10000, 10999)),
+				protoFile("user/v1/account.proto", pkg, withEnumRange(enumType("AccountError", 500,
+					enumValue("ACCOUNT_UNKNOWN", 0, withCode(0)), enumValue("ACCOUNT_LOCKED", 1)), 11000, 11999)),
+			},
+			contains: []string{"errors.New(10001, 500, UserError_USER_EXISTS.String()"},
+		},
+		{
+			name:  "bad default_code",
+			files: []*descriptorpb.FileDescriptorProto{userFile(enumType("UserError", -1, enumValue("USER_UNKNOWN", 0)))},
+			err:   "user.v1.UserError: default_code -1 must be greater than 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := runPlugin(t, "", tt.files...)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expect error %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			content := out["user/v1/user_errors.pb.go"]
+			for _, sub := range tt.contains {
+				if !strings.Contains(content, sub) {
+					t.Errorf("generated file does not contain %q\n%s", sub, content)
+				}
+			}
+		})
+	}
+}
@@ -9,10 +9,11 @@ const (
 )
 {{ end }}
 {{ if .GenDesc}}
+// 以下错误变量在多个goroutine间共享, 需要修改时使用errors.Clone得到副本, 例如 errors.Clone(e).WithMetadata(k, v)
 var (
 	{{- range .Errors }}
 	{{- if ne .Desc "" }}
-	Error{{ .Name }} = errors.New(int32({{ .EnumName }}_{{ .Name }}), {{ .HTTPStatus }}, "{{ .Name }}", Desc_{{ .Name }})
+	Error{{ .Name }} = errors.New({{ .Code }}, {{ .HTTPStatus }}, "{{ .Name }}", Desc_{{ .Name }})
 	{{- end}}
 	{{- end }}
 )
@@ -21,7 +22,32 @@ var (
 {{ range .Errors}}
 {{ if ne .Comment ""}}{{ .Comment }}{{ end -}}
 func NewError{{ .CamelName }}(format string, args ...interface{}) errors.Error {
-	return errors.New(int32({{ .EnumName }}_{{ .Name }}), {{ .HTTPStatus }}, {{ .EnumName }}_{{ .Name }}.String(), fmt.Sprintf(format, args...))
+	return errors.New({{ .Code }}, {{ .HTTPStatus }}, {{ .EnumName }}_{{ .Name }}.String(), fmt.Sprintf(format, args...))
 }
 
+// Is{{ .CamelName }} 判断err(包括被包装的err)的reason和code是否为{{ .Name }}
+func Is{{ .CamelName }}(err error) bool {
+	if err == nil {
+		return false
+	}
+	e := errors.Convert(err)
+	return e.Reason() == {{ .EnumName }}_{{ .Name }}.String() && e.Code() == {{ .Code }}
+}
+
+{{ end }}
+{{ define "register" }}
+// 注册错误, 可以通过errors.Lookup根据错误码查找, 错误码0保留给成功, 不会注册
+func init() {
+	errors.Register(
+		{{- range . }}
+		{{- if ne .Code 0 }}
+		{{- if ne .Desc "" }}
+		Error{{ .Name }},
+		{{- else }}
+		errors.New({{ .Code }}, {{ .HTTPStatus }}, "{{ .Name }}", ""),
+		{{- end }}
+		{{- end }}
+		{{- end }}
+	)
+}
 {{ end }}
@@ -12,9 +12,10 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
-func generateFile(gen *protogen.Plugin, file *protogen.File) *protogen.GeneratedFile {
+// generateFile 生成错误定义, 返回文件中定义的所有错误, 用于生成错误目录
+func generateFile(gen *protogen.Plugin, file *protogen.File) ([]*ErrorDesc, error) {
 	if len(file.Enums) == 0 {
-		return nil
+		return nil, nil
 	}
 	filename := file.GeneratedFilenamePrefix + "_errors.pb.go"
 	g := gen.NewGeneratedFile(filename, file.GoImportPath)
@@ -38,29 +39,38 @@ func generateFile(gen *protogen.Plugin, file *protogen.File) *protogen.Generated
 	g.P(`"github.com/mangohow/mangokit/errors"`)
 	g.P(")")
 
-	generateFileContent(gen, file, g)
-
-	return g
+	return generateFileContent(gen, file, g)
 }
 
-func generateFileContent(gen *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile) {
+func generateFileContent(gen *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile) ([]*ErrorDesc, error) {
 	if len(file.Enums) == 0 {
-		return
+		return nil, nil
 	}
 
-	index := 0
+	var descs []*ErrorDesc
 	for _, enum := range file.Enums {
-		if !genErrorsReason(gen, file, g, enum) {
-			index++
+		errs, err := genErrorsReason(gen, file, g, enum)
+		if err != nil {
+			return nil, err
 		}
+		descs = append(descs, errs...)
 	}
 	// If all enums do not contain 'mangokit.code', the current file is skipped
-	if index == 0 {
+	if len(descs) == 0 {
 		g.Skip()
+		return nil, nil
+	}
+
+	content, err := registerErrors(descs)
+	if err != nil {
+		return nil, err
 	}
+	g.P(content)
+
+	return descs, nil
 }
 
-func genErrorsReason(gen *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile, enum *protogen.Enum) bool {
+func genErrorsReason(gen *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile, enum *protogen.Enum) ([]*ErrorDesc, error) {
 	defaultCode := proto.GetExtension(enum.Desc.Options(), errors.E_DefaultCode)
 
 	code := 0
@@ -68,7 +78,12 @@ func genErrorsReason(gen *protogen.Plugin, file *protogen.File, g *protogen.Gene
 		code = int(ok)
 	}
 	if code > 600 || code < 0 {
-		panic(fmt.Sprintf("Enum '%s' range must be greater than 0 and less than or equal to 600", string(enum.Desc.Name())))
+		return nil, fmt.Errorf("%s: default_code %d must be greater than 0 and less than or equal to 600", enum.Desc.FullName(), code)
+	}
+
+	codeRange, err := enumCodeRange(file, enum)
+	if err != nil {
+		return nil, err
 	}
 
 	var ees EnumErrors
@@ -87,13 +102,18 @@ func genErrorsReason(gen *protogen.Plugin, file *protogen.File, g *protogen.Gene
 		// If the current enumeration does not contain 'mangokit.code'
 		// or the code value exceeds the range, the current enum will be skipped
 		if status > 600 || status < 0 {
-			panic(fmt.Sprintf("Enum '%s' range must be greater than 0 and less than or equal to 600", string(value.Desc.Name())))
+			return nil, fmt.Errorf("%s.%s: code %d must be greater than 0 and less than or equal to 600", enum.Desc.FullName(), value.Desc.Name(), status)
 		}
 
 		if status == 0 {
 			continue
 		}
 
+		bc, err := bizCode(value, codeRange)
+		if err != nil {
+			return nil, err
+		}
+
 		// 注释
 		comment := value.Comments.Leading.String()
 		if comment == "" {
@@ -107,18 +127,26 @@ func genErrorsReason(gen *protogen.Plugin, file *protogen.File, g *protogen.Gene
 			HTTPStatus: status,
 			EnumName:   case2Camel(string(enum.Desc.Name())),
 			Desc:       desc,
+			Code:       bc,
+			FullName:   string(enum.Desc.FullName()),
+			Source:     file.Desc.Path(),
+			Assigned:   codeRange != nil || proto.HasExtension(value.Desc.Options(), errors.E_BizCode),
 		}
 
 		ees.Errors = append(ees.Errors, e)
 	}
 
 	if len(ees.Errors) == 0 {
-		return true
+		return nil, nil
 	}
 
-	g.P(ees.execute())
+	content, err := ees.execute()
+	if err != nil {
+		return nil, err
+	}
+	g.P(content)
 
-	return false
+	return ees.Errors, nil
 }
 
 var enCases = cases.Title(language.AmericanEnglish, cases.NoLower)
@@ -18,16 +18,56 @@ func main() {
 	}
 
 	var flags flag.FlagSet
+	// catalog 生成错误目录, 可选值为json, markdown, all, 例如 --go-error_opt=catalog=all
+	catalog := flags.String("catalog", "", "generate error catalog: json, markdown or all")
 	protogen.Options{
 		ParamFunc: flags.Set,
 	}.Run(func(gen *protogen.Plugin) error {
 		gen.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
-		for _, f := range gen.Files {
-			if !f.Generate {
-				continue
-			}
-			generateFile(gen, f)
+		return generate(gen, *catalog)
+	})
+}
+
+// generate 为所有需要生成的文件生成错误定义, catalog不为空时生成错误目录
+func generate(gen *protogen.Plugin, catalog string) error {
+	if !validCatalogFormat(catalog) {
+		return fmt.Errorf("invalid catalog format %q, must be json, markdown or all", catalog)
+	}
+
+	var (
+		files []*protogen.File
+		descs = make(map[*protogen.File][]*ErrorDesc)
+	)
+	for _, f := range gen.Files {
+		if f.Generate {
+			files = append(files, f)
+		}
+	}
+	// 同一个package中的错误码范围不能重叠
+	if err := checkCodeRanges(files); err != nil {
+		return err
+	}
+	for _, f := range files {
+		d, err := generateFile(gen, f)
+		if err != nil {
+			return err
 		}
+		descs[f] = d
+	}
+
+	// 同一个package中的错误码不能重复
+	catalogs, err := buildCatalogs(files, descs)
+	if err != nil {
+		return err
+	}
+	if catalog == "" {
 		return nil
-	})
+	}
+	for _, c := range catalogs {
+		if err := generateCatalog(gen, c, catalog); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
@@ -16,6 +16,10 @@ type ErrorDesc struct {
 	HTTPStatus int    // http响应码
 	EnumName   string // 枚举名称
 	Desc       string // 错误描述
+	Code       int32  // 业务错误码, 由biz_code或者错误码范围计算得到, 默认为枚举值
+	FullName   string // 枚举的proto完整名称
+	Source     string // proto文件路径
+	Assigned   bool   // 错误码是否由biz_code或者错误码范围指定, 只有指定的错误码参与重复检查
 }
 
 type EnumErrors struct {
@@ -23,14 +27,26 @@ type EnumErrors struct {
 	GenDesc bool // 是否生成Desc
 }
 
-func (e EnumErrors) execute() string {
+func (e EnumErrors) execute() (string, error) {
+	return executeTemplate("", e)
+}
+
+// registerErrors 生成文件中所有错误的注册代码, 每个文件只生成一个init函数
+func registerErrors(errs []*ErrorDesc) (string, error) {
+	return executeTemplate("register", errs)
+}
+
+func executeTemplate(name string, data interface{}) (string, error) {
 	buf := new(bytes.Buffer)
 	tmpl, err := template.New("mangokit").Parse(ErrorTemplate)
 	if err != nil {
-		panic(err)
+		return "", err
+	}
+	if name != "" {
+		tmpl = tmpl.Lookup(name)
 	}
-	if err := tmpl.Execute(buf, e); err != nil {
-		panic(err)
+	if err := tmpl.Execute(buf, data); err != nil {
+		return "", err
 	}
-	return buf.String()
+	return buf.String(), nil
 }
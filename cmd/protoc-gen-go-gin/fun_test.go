@@ -27,6 +27,12 @@ func TestValidatePath(t *testing.T) {
 		"/abc/:abc123",
 		"",
 		"/test/:a:b",
+		"/api/user/:id/books",
+		"/api/shelf/:shelf.id/book/:book_id",
+		"/static/*filepath",
+		"/static/*filepath/abc",
+		"/api/:a./b",
+		"/api/v1.0/user-info",
 	}
 
 	for _, path := range paths {
@@ -133,24 +133,28 @@ func buildHTTPRule(g *protogen.GeneratedFile, service *protogen.Service, m *prot
 	}
 
 	md := buildMethodDesc(g, m)
+	md.FullMethod = fmt.Sprintf("/%s/%s", service.Desc.FullName(), m.Desc.Name())
 	md.Method = strings.ToUpper(method)
 	md.Path = path
 	md.ServiceName = service.GoName
 	md.LowerServiceName = strings.ToLower(md.ServiceName)
 
-	// 判断pattern中是否存在param
-	if strings.Contains(md.Path, ":") {
+	// 判断pattern中是否存在param或者catch-all参数
+	if strings.ContainsAny(md.Path, ":*") {
 		md.EncodeParam = true
 	}
 
 	// 如果是get请求，并且param参数小于结构体中的field数量，才可能有query参数
-	if method == "GET" && strings.Count(md.Path, ":") < md.InputFieldLen {
+	if method == "GET" && strings.Count(md.Path, ":")+strings.Count(md.Path, "*") < md.InputFieldLen {
 		md.EncodeForm = true
 	}
 
 	return md
 }
 
+// pathPattern 参数可以位于任意位置, 嵌套字段使用 :a.b 表示, catch-all参数 *name 只能位于最后
+var pathPattern = regexp.MustCompile(`^(/([a-zA-Z0-9_.-]+|:[a-zA-Z_][a-zA-Z0-9_]*(\.[a-zA-Z_][a-zA-Z0-9_]*)*))*(/\*[a-zA-Z_][a-zA-Z0-9_]*)?$`)
+
 func validatePath(path string) bool {
 	if path == "" {
 		return false
@@ -158,10 +162,7 @@ func validatePath(path string) bool {
 	if path == "/" {
 		return true
 	}
-	pathPattern := `^(/[a-zA-z0-9]+)*(/:[a-zA-z]+[0-9]*)*$`
-	// 编译正则表达式
-	re := regexp.MustCompile(pathPattern)
-	return re.MatchString(path)
+	return pathPattern.MatchString(path)
 }
 
 func buildMethodDesc(g *protogen.GeneratedFile, m *protogen.Method) *MethodDesc {
@@ -19,8 +19,8 @@ type {{.ServiceName}}HTTPService interface {
 {{- end}}
 }
 
-func Register{{.ServiceName}}HTTPService(server *http.Server, svc {{.ServiceName}}HTTPService) {
-    server.RegisterService(_{{.ServiceName}}HTTPService_serviceDesc, svc)
+func Register{{.ServiceName}}HTTPService(server *http.Server, svc {{.ServiceName}}HTTPService, opts ...http.RegisterOption) {
+    server.RegisterService(_{{.ServiceName}}HTTPService_serviceDesc, svc, opts...)
 }
 
 {{range .Methods}}
@@ -113,6 +113,7 @@ func (c *{{.LowerServiceName}}HTTPClient) {{.Name}}(ctx context.Context, opts ..
     {{- else}}
     path := "{{.Path}}"
     {{- end}}
+    opts = append([]http.CallOption{http.OperationCallOption("{{.FullMethod}}"), http.PathTemplateCallOption("{{.Path}}")}, opts...)
 	{{- if and (ne .InputFieldLen 0) (ne .OutputFieldLen 0)}}
     _, err := c.cc.Invoke(ctx, "{{.Method}}", path, req, reply, opts...)
     {{- else if ne .InputFieldLen 0}}
@@ -124,7 +125,10 @@ func (c *{{.LowerServiceName}}HTTPClient) {{.Name}}(ctx context.Context, opts ..
     {{- end}}
 	
     {{if ne .OutputFieldLen 0}}
-	return reply, err
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
     {{else}}
     return err
     {{- end -}}
@@ -132,13 +136,16 @@ func (c *{{.LowerServiceName}}HTTPClient) {{.Name}}(ctx context.Context, opts ..
 {{end}}
 
 var _{{.ServiceName}}HTTPService_serviceDesc = &http.ServiceDesc{
+	ServiceName: "{{.ServiceName}}",
 	HandlerType: (*{{.ServiceName}}HTTPService)(nil),
 	Methods: []http.MethodDesc{
 	{{- range .Methods}}
 		{
-			Method:  "{{.Method}}",
-			Path:    "{{.Path}}",
-			Handler: _{{.ServiceName}}_{{.Name}}_HTTP_Handler,
+			Name:       "{{.Name}}",
+			FullMethod: "{{.FullMethod}}",
+			Method:     "{{.Method}}",
+			Path:       "{{.Path}}",
+			Handler:    _{{.ServiceName}}_{{.Name}}_HTTP_Handler,
 		},
 	{{- end}}
 	},
@@ -22,6 +22,7 @@ type ServiceDesc struct {
 
 type MethodDesc struct {
 	Name           string // 方法名
+	FullMethod     string // proto完整方法名, 例如 /helloworld.v1.Greeter/SayHello
 	Request        string // 请求参数名
 	Reply          string // 响应参数名
 	ServiceName    string // 所属service名
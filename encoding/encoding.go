@@ -0,0 +1,64 @@
+package encoding
+
+import (
+	"mime"
+	"strings"
+	"sync"
+)
+
+// Codec 编解码器, 用于请求体和响应体的序列化
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+	// ContentType 序列化后的数据使用的Content-Type
+	ContentType() string
+}
+
+var (
+	mu     sync.RWMutex
+	codecs = make(map[string]Codec)
+)
+
+// RegisterCodec 注册codec, contentTypes为codec可以处理的Content-Type, 为空时使用codec.ContentType()
+func RegisterCodec(codec Codec, contentTypes ...string) {
+	if codec == nil {
+		panic("cannot register a nil Codec")
+	}
+	if len(contentTypes) == 0 {
+		contentTypes = []string{codec.ContentType()}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, ct := range contentTypes {
+		codecs[normalize(ct)] = codec
+	}
+}
+
+// GetCodec 根据Content-Type获取codec, 会忽略charset等参数, 未注册时返回nil
+func GetCodec(contentType string) Codec {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return codecs[normalize(contentType)]
+}
+
+// NegotiateCodec 根据Accept头选择codec, 按照Accept中出现的顺序选择第一个已注册的codec
+func NegotiateCodec(accept string) Codec {
+	for _, ct := range strings.Split(accept, ",") {
+		if codec := GetCodec(ct); codec != nil {
+			return codec
+		}
+	}
+
+	return nil
+}
+
+func normalize(contentType string) string {
+	contentType = strings.TrimSpace(contentType)
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mt
+	}
+
+	return strings.ToLower(contentType)
+}
@@ -0,0 +1,78 @@
+package encoding_test
+
+import (
+	"testing"
+
+	"github.com/mangohow/mangokit/encoding"
+	"github.com/mangohow/mangokit/encoding/json"
+	"github.com/mangohow/mangokit/encoding/msgpack"
+	"github.com/mangohow/mangokit/encoding/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestGetCodec(t *testing.T) {
+	tests := []struct {
+		contentType string
+		expect      string
+	}{
+		{"application/json", json.ContentType},
+		{"application/json; charset=utf-8", json.ContentType},
+		{"application/x-protobuf", proto.ContentType},
+		{"application/msgpack", msgpack.ContentType},
+		{"text/html", ""},
+	}
+	for _, tt := range tests {
+		codec := encoding.GetCodec(tt.contentType)
+		if tt.expect == "" {
+			if codec != nil {
+				t.Errorf("%s: expect nil codec", tt.contentType)
+			}
+			continue
+		}
+		if codec == nil || codec.ContentType() != tt.expect {
+			t.Errorf("%s: unexpected codec %v", tt.contentType, codec)
+		}
+	}
+
+	codec := encoding.NegotiateCodec("text/html, application/x-msgpack;q=0.9, */*")
+	if codec == nil || codec.ContentType() != msgpack.ContentType {
+		t.Errorf("negotiate: unexpected codec %v", codec)
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	type item struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+
+	data, err := encoding.GetCodec(json.ContentType).Marshal(wrapperspb.Int64(42))
+	if err != nil || string(data) != `"42"` {
+		t.Errorf("protojson: got %s, err %v", data, err)
+	}
+
+	for _, ct := range []string{json.ContentType, msgpack.ContentType} {
+		codec := encoding.GetCodec(ct)
+		data, err := codec.Marshal(&item{ID: 1, Name: "mango"})
+		if err != nil {
+			t.Fatalf("%s: marshal error %v", ct, err)
+		}
+		var out item
+		if err = codec.Unmarshal(data, &out); err != nil || out.ID != 1 || out.Name != "mango" {
+			t.Errorf("%s: unmarshal got %+v, err %v", ct, out, err)
+		}
+	}
+
+	codec := encoding.GetCodec(proto.ContentType)
+	data, err = codec.Marshal(wrapperspb.String("mango"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(wrapperspb.StringValue)
+	if err = codec.Unmarshal(data, out); err != nil || out.GetValue() != "mango" {
+		t.Errorf("proto: unmarshal got %v, err %v", out, err)
+	}
+	if _, err = codec.Marshal(&item{}); err == nil {
+		t.Error("proto: expect error for non proto message")
+	}
+}
@@ -0,0 +1,50 @@
+package json
+
+import (
+	"encoding/json"
+
+	"github.com/mangohow/mangokit/encoding"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const ContentType = "application/json"
+
+var (
+	// MarshalOptions proto.Message使用protojson序列化时的选项
+	MarshalOptions = protojson.MarshalOptions{
+		UseProtoNames: true,
+	}
+
+	// UnmarshalOptions proto.Message使用protojson反序列化时的选项
+	UnmarshalOptions = protojson.UnmarshalOptions{
+		DiscardUnknown: true,
+	}
+)
+
+func init() {
+	encoding.RegisterCodec(codec{}, ContentType, "application/protojson", "text/json")
+}
+
+// codec proto.Message使用protojson, 其他类型使用encoding/json
+type codec struct{}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	if m, ok := v.(proto.Message); ok {
+		return MarshalOptions.Marshal(m)
+	}
+
+	return json.Marshal(v)
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	if m, ok := v.(proto.Message); ok {
+		return UnmarshalOptions.Unmarshal(data, m)
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func (codec) ContentType() string {
+	return ContentType
+}
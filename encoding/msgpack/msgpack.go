@@ -0,0 +1,33 @@
+package msgpack
+
+import (
+	"github.com/mangohow/mangokit/encoding"
+	"github.com/ugorji/go/codec"
+)
+
+const ContentType = "application/x-msgpack"
+
+var handle = &codec.MsgpackHandle{}
+
+func init() {
+	handle.RawToString = true
+	encoding.RegisterCodec(msgpackCodec{}, ContentType, "application/msgpack")
+}
+
+// msgpackCodec msgpack编码, 字段名与json tag保持一致
+type msgpackCodec struct{}
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var data []byte
+	err := codec.NewEncoderBytes(&data, handle).Encode(v)
+
+	return data, err
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	return codec.NewDecoderBytes(data, handle).Decode(v)
+}
+
+func (msgpackCodec) ContentType() string {
+	return ContentType
+}
@@ -0,0 +1,39 @@
+package proto
+
+import (
+	"fmt"
+
+	"github.com/mangohow/mangokit/encoding"
+	"google.golang.org/protobuf/proto"
+)
+
+const ContentType = "application/x-protobuf"
+
+func init() {
+	encoding.RegisterCodec(codec{}, ContentType, "application/protobuf")
+}
+
+// codec protobuf二进制编码, 只能处理proto.Message
+type codec struct{}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("proto: %T is not a proto.Message", v)
+	}
+
+	return proto.Marshal(m)
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("proto: %T is not a proto.Message", v)
+	}
+
+	return proto.Unmarshal(data, m)
+}
+
+func (codec) ContentType() string {
+	return ContentType
+}
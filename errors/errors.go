@@ -1,6 +1,7 @@
 package errors
 
 import (
+	stderr "errors"
 	"fmt"
 	"net/http"
 )
@@ -25,6 +26,7 @@ type Error interface {
 type ErrorImpl struct {
 	cause     error
 	status    int32
+	stack     stack
 	Code_     int32             `json:"code"`
 	Reason_   string            `json:"reason"`
 	Message_  string            `json:"message"`
@@ -65,9 +67,107 @@ func (e *ErrorImpl) Unwrap() error {
 	return e.cause
 }
 
+// Is 实现errors.Is, target为Error并且reason和code都相同时返回true
+func (e *ErrorImpl) Is(target error) bool {
+	t, ok := target.(Error)
+	if !ok {
+		return false
+	}
+
+	return t.Reason() == e.Reason_ && t.Code() == e.Code_
+}
+
+// Stack 创建错误时的调用栈, 需要使用EnableStack开启
+func (e *ErrorImpl) Stack() string {
+	return e.stack.String()
+}
+
+// 以下方法不会修改原错误, 而是返回修改后的副本, 因此可以在共享的错误变量上直接调用
+// 类型为Error的错误可以先使用Clone转换为*ErrorImpl, 例如 errors.Clone(e).WithMetadata("id", "1")
+
+func (e *ErrorImpl) WithMetadata(key, value string) *ErrorImpl {
+	c := e.clone()
+	if c.Metadata_ == nil {
+		c.Metadata_ = make(map[string]string, 1)
+	}
+	c.Metadata_[key] = value
+
+	return c
+}
+
+func (e *ErrorImpl) WithCause(err error) *ErrorImpl {
+	c := e.clone()
+	c.cause = err
+
+	return c
+}
+
+func (e *ErrorImpl) WithMessage(message string) *ErrorImpl {
+	c := e.clone()
+	c.Message_ = message
+
+	return c
+}
+
+func (e *ErrorImpl) WithMessagef(format string, args ...interface{}) *ErrorImpl {
+	c := e.clone()
+	c.Message_ = fmt.Sprintf(format, args...)
+
+	return c
+}
+
+// Clone 返回错误的副本, 需要直接修改ErrorImpl的字段时使用
+func (e *ErrorImpl) Clone() *ErrorImpl {
+	return e.clone()
+}
+
+// Clone 将err复制为*ErrorImpl, 其他Error的实现会复制其中的字段, err为nil时返回nil
+func Clone(err Error) *ErrorImpl {
+	if err == nil {
+		return nil
+	}
+	if e, ok := err.(*ErrorImpl); ok {
+		return e.clone()
+	}
+
+	e := &ErrorImpl{
+		cause:    err.Unwrap(),
+		status:   err.HttpStatus(),
+		stack:    callers(),
+		Code_:    err.Code(),
+		Reason_:  err.Reason(),
+		Message_: err.Message(),
+	}
+	if md := err.Metadata(); md != nil {
+		e.Metadata_ = make(map[string]string, len(md)+1)
+		for k, v := range md {
+			e.Metadata_[k] = v
+		}
+	}
+
+	return e
+}
+
+// clone 复制错误以及Metadata, 原错误没有调用栈时(例如在init阶段创建的错误变量)记录当前的调用栈
+func (e *ErrorImpl) clone() *ErrorImpl {
+	c := *e
+	if e.Metadata_ != nil {
+		c.Metadata_ = make(map[string]string, len(e.Metadata_)+1)
+		for k, v := range e.Metadata_ {
+			c.Metadata_[k] = v
+		}
+	}
+	if c.stack == nil {
+		c.stack = callers()
+	}
+
+	return &c
+}
+
 func New(code, status int32, reason, message string) Error {
 	return &ErrorImpl{
 		status:   status,
+		stack:    callers(),
 		Code_:    code,
 		Reason_:  reason,
 		Message_: message,
@@ -75,25 +175,70 @@ func New(code, status int32, reason, message string) Error {
 }
 
 func Newf(code, status int32, reason, format string, args ...interface{}) Error {
-	return Newf(code, status, reason, fmt.Sprintf(format, args...))
+	return New(code, status, reason, fmt.Sprintf(format, args...))
 }
 
-func FromError(code, status int32, reason, message string, err error) Error {
+// Wrap 创建Error, err作为错误的cause
+func Wrap(code, status int32, reason, message string, err error) Error {
 	return &ErrorImpl{
 		cause:    err,
 		status:   status,
+		stack:    callers(),
 		Code_:    code,
 		Reason_:  reason,
 		Message_: message,
 	}
 }
 
+func Wrapf(code, status int32, reason string, err error, format string, args ...interface{}) Error {
+	return Wrap(code, status, reason, fmt.Sprintf(format, args...), err)
+}
+
+// FromError 创建Error, err作为错误的cause
+//
+// Deprecated: 使用Wrap代替
+func FromError(code, status int32, reason, message string, err error) Error {
+	return Wrap(code, status, reason, message, err)
+}
+
+// FromErrorf 创建Error, err作为错误的cause
+//
+// Deprecated: 使用Wrapf代替
 func FromErrorf(code, status int32, reason string, err error, format string, args ...interface{}) Error {
-	return FromError(code, status, reason, fmt.Sprintf(format, args...), err)
+	return Wrap(code, status, reason, fmt.Sprintf(format, args...), err)
+}
+
+// Convert 沿着Unwrap链查找Error, 找不到时返回包装了err的UnknownReason错误, err为nil时返回nil
+func Convert(err error) Error {
+	if err == nil {
+		return nil
+	}
+	var e Error
+	if stderr.As(err, &e) {
+		return e
+	}
+
+	return Wrap(UnknownCode, DefaultStatus, UnknownReason, UnknownMessage, err)
 }
 
+// IsError 沿着Unwrap链查找, err中包含Error时返回true
 func IsError(err error) bool {
-	_, ok := err.(Error)
+	var e Error
+
+	return stderr.As(err, &e)
+}
+
+// Is 与标准库errors.Is相同, 对于Error, reason和code相同即认为是同一个错误
+func Is(err, target error) bool {
+	return stderr.Is(err, target)
+}
+
+// As 与标准库errors.As相同
+func As(err error, target interface{}) bool {
+	return stderr.As(err, target)
+}
 
-	return ok
+// Unwrap 与标准库errors.Unwrap相同
+func Unwrap(err error) error {
+	return stderr.Unwrap(err)
 }
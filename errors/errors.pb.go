@@ -1,8 +1,8 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.34.1-devel
+// 	protoc-gen-go v1.34.1
 // 	protoc        v3.20.1
-// source: third_party/mangokit/mangokit.proto
+// source: third_party/errors/errors.proto
 
 package errors
 
@@ -11,6 +11,7 @@ import (
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
 	reflect "reflect"
+	sync "sync"
 )
 
 const (
@@ -20,45 +21,134 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type CodeRange struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Start int32 `protobuf:"varint,1,opt,name=start,proto3" json:"start,omitempty"`
+	End   int32 `protobuf:"varint,2,opt,name=end,proto3" json:"end,omitempty"`
+}
+
+func (x *CodeRange) Reset() {
+	*x = CodeRange{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_third_party_errors_errors_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CodeRange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CodeRange) ProtoMessage() {}
+
+func (x *CodeRange) ProtoReflect() protoreflect.Message {
+	mi := &file_third_party_errors_errors_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CodeRange.ProtoReflect.Descriptor instead.
+func (*CodeRange) Descriptor() ([]byte, []int) {
+	return file_third_party_errors_errors_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CodeRange) GetStart() int32 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *CodeRange) GetEnd() int32 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
 var file_third_party_errors_errors_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.FileOptions)(nil),
+		ExtensionType: (*CodeRange)(nil),
+		Field:         1111,
+		Name:          "errors.code_range",
+		Tag:           "bytes,1111,opt,name=code_range",
+		Filename:      "third_party/errors/errors.proto",
+	},
 	{
 		ExtendedType:  (*descriptorpb.EnumOptions)(nil),
 		ExtensionType: (*int32)(nil),
 		Field:         1108,
-		Name:          "mangokit.default_code",
+		Name:          "errors.default_code",
 		Tag:           "varint,1108,opt,name=default_code",
-		Filename:      "third_party/mangokit/mangokit.proto",
+		Filename:      "third_party/errors/errors.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.EnumOptions)(nil),
+		ExtensionType: (*CodeRange)(nil),
+		Field:         1112,
+		Name:          "errors.enum_code_range",
+		Tag:           "bytes,1112,opt,name=enum_code_range",
+		Filename:      "third_party/errors/errors.proto",
 	},
 	{
 		ExtendedType:  (*descriptorpb.EnumValueOptions)(nil),
 		ExtensionType: (*int32)(nil),
 		Field:         1109,
-		Name:          "mangokit.code",
+		Name:          "errors.code",
 		Tag:           "varint,1109,opt,name=code",
-		Filename:      "third_party/mangokit/mangokit.proto",
+		Filename:      "third_party/errors/errors.proto",
 	},
 	{
 		ExtendedType:  (*descriptorpb.EnumValueOptions)(nil),
 		ExtensionType: (*string)(nil),
 		Field:         1110,
-		Name:          "mangokit.desc",
+		Name:          "errors.desc",
 		Tag:           "bytes,1110,opt,name=desc",
-		Filename:      "third_party/mangokit/mangokit.proto",
+		Filename:      "third_party/errors/errors.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.EnumValueOptions)(nil),
+		ExtensionType: (*int32)(nil),
+		Field:         1113,
+		Name:          "errors.biz_code",
+		Tag:           "varint,1113,opt,name=biz_code",
+		Filename:      "third_party/errors/errors.proto",
 	},
 }
 
+// Extension fields to descriptorpb.FileOptions.
+var (
+	// optional errors.CodeRange code_range = 1111;
+	E_CodeRange = &file_third_party_errors_errors_proto_extTypes[0]
+)
+
 // Extension fields to descriptorpb.EnumOptions.
 var (
 	// optional int32 default_code = 1108;
-	E_DefaultCode = &file_third_party_errors_errors_proto_extTypes[0]
+	E_DefaultCode = &file_third_party_errors_errors_proto_extTypes[1]
+	// optional errors.CodeRange enum_code_range = 1112;
+	E_EnumCodeRange = &file_third_party_errors_errors_proto_extTypes[2]
 )
 
 // Extension fields to descriptorpb.EnumValueOptions.
 var (
 	// optional int32 code = 1109;
-	E_Code = &file_third_party_errors_errors_proto_extTypes[1]
+	E_Code = &file_third_party_errors_errors_proto_extTypes[3]
 	// optional string desc = 1110;
-	E_Desc = &file_third_party_errors_errors_proto_extTypes[2]
+	E_Desc = &file_third_party_errors_errors_proto_extTypes[4]
+	// optional int32 biz_code = 1113;
+	E_BizCode = &file_third_party_errors_errors_proto_extTypes[5]
 )
 
 var File_third_party_errors_errors_proto protoreflect.FileDescriptor
@@ -68,36 +158,74 @@ var file_third_party_errors_errors_proto_rawDesc = []byte{
 	0x72, 0x6f, 0x72, 0x73, 0x2f, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74,
 	0x6f, 0x12, 0x06, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x1a, 0x20, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
 	0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x64, 0x65, 0x73, 0x63, 0x72,
-	0x69, 0x70, 0x74, 0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x3a, 0x40, 0x0a, 0x0c, 0x64,
-	0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x5f, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x1c, 0x2e, 0x67, 0x6f,
-	0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6e,
-	0x75, 0x6d, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0xd4, 0x08, 0x20, 0x01, 0x28, 0x05,
-	0x52, 0x0b, 0x64, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x43, 0x6f, 0x64, 0x65, 0x3a, 0x36, 0x0a,
+	0x69, 0x70, 0x74, 0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x33, 0x0a, 0x09, 0x43,
+	0x6f, 0x64, 0x65, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x72,
+	0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x73, 0x74, 0x61, 0x72, 0x74, 0x12, 0x10,
+	0x0a, 0x03, 0x65, 0x6e, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x03, 0x65, 0x6e, 0x64,
+	0x3a, 0x4f, 0x0a, 0x0a, 0x63, 0x6f, 0x64, 0x65, 0x5f, 0x72, 0x61, 0x6e, 0x67, 0x65, 0x12, 0x1c,
+	0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
+	0x2e, 0x46, 0x69, 0x6c, 0x65, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0xd7, 0x08, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x2e, 0x43, 0x6f, 0x64,
+	0x65, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x52, 0x09, 0x63, 0x6f, 0x64, 0x65, 0x52, 0x61, 0x6e, 0x67,
+	0x65, 0x3a, 0x40, 0x0a, 0x0c, 0x64, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x5f, 0x63, 0x6f, 0x64,
+	0x65, 0x12, 0x1c, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x62, 0x75, 0x66, 0x2e, 0x45, 0x6e, 0x75, 0x6d, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18,
+	0xd4, 0x08, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0b, 0x64, 0x65, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x43,
+	0x6f, 0x64, 0x65, 0x3a, 0x58, 0x0a, 0x0f, 0x65, 0x6e, 0x75, 0x6d, 0x5f, 0x63, 0x6f, 0x64, 0x65,
+	0x5f, 0x72, 0x61, 0x6e, 0x67, 0x65, 0x12, 0x1c, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6e, 0x75, 0x6d, 0x4f, 0x70, 0x74,
+	0x69, 0x6f, 0x6e, 0x73, 0x18, 0xd8, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x65, 0x72,
+	0x72, 0x6f, 0x72, 0x73, 0x2e, 0x43, 0x6f, 0x64, 0x65, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x52, 0x0d,
+	0x65, 0x6e, 0x75, 0x6d, 0x43, 0x6f, 0x64, 0x65, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x3a, 0x36, 0x0a,
 	0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x21, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70,
 	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6e, 0x75, 0x6d, 0x56, 0x61, 0x6c, 0x75,
 	0x65, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0xd5, 0x08, 0x20, 0x01, 0x28, 0x05, 0x52,
 	0x04, 0x63, 0x6f, 0x64, 0x65, 0x3a, 0x36, 0x0a, 0x04, 0x64, 0x65, 0x73, 0x63, 0x12, 0x21, 0x2e,
 	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e,
 	0x45, 0x6e, 0x75, 0x6d, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73,
-	0x18, 0xd6, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x64, 0x65, 0x73, 0x63, 0x42, 0x2c, 0x5a,
-	0x2a, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6d, 0x61, 0x6e, 0x67,
-	0x6f, 0x68, 0x6f, 0x77, 0x2f, 0x6d, 0x61, 0x6e, 0x67, 0x6f, 0x6b, 0x69, 0x74, 0x2f, 0x65, 0x72,
-	0x72, 0x6f, 0x72, 0x73, 0x3b, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x33,
+	0x18, 0xd6, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x64, 0x65, 0x73, 0x63, 0x3a, 0x3d, 0x0a,
+	0x08, 0x62, 0x69, 0x7a, 0x5f, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x21, 0x2e, 0x67, 0x6f, 0x6f, 0x67,
+	0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6e, 0x75, 0x6d,
+	0x56, 0x61, 0x6c, 0x75, 0x65, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0xd9, 0x08, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x07, 0x62, 0x69, 0x7a, 0x43, 0x6f, 0x64, 0x65, 0x42, 0x2c, 0x5a, 0x2a,
+	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6d, 0x61, 0x6e, 0x67, 0x6f,
+	0x68, 0x6f, 0x77, 0x2f, 0x6d, 0x61, 0x6e, 0x67, 0x6f, 0x6b, 0x69, 0x74, 0x2f, 0x65, 0x72, 0x72,
+	0x6f, 0x72, 0x73, 0x3b, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x33,
 }
 
+var (
+	file_third_party_errors_errors_proto_rawDescOnce sync.Once
+	file_third_party_errors_errors_proto_rawDescData = file_third_party_errors_errors_proto_rawDesc
+)
+
+func file_third_party_errors_errors_proto_rawDescGZIP() []byte {
+	file_third_party_errors_errors_proto_rawDescOnce.Do(func() {
+		file_third_party_errors_errors_proto_rawDescData = protoimpl.X.CompressGZIP(file_third_party_errors_errors_proto_rawDescData)
+	})
+	return file_third_party_errors_errors_proto_rawDescData
+}
+
+var file_third_party_errors_errors_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
 var file_third_party_errors_errors_proto_goTypes = []interface{}{
-	(*descriptorpb.EnumOptions)(nil),      // 0: google.protobuf.EnumOptions
-	(*descriptorpb.EnumValueOptions)(nil), // 1: google.protobuf.EnumValueOptions
+	(*CodeRange)(nil),                     // 0: errors.CodeRange
+	(*descriptorpb.FileOptions)(nil),      // 1: google.protobuf.FileOptions
+	(*descriptorpb.EnumOptions)(nil),      // 2: google.protobuf.EnumOptions
+	(*descriptorpb.EnumValueOptions)(nil), // 3: google.protobuf.EnumValueOptions
 }
 var file_third_party_errors_errors_proto_depIdxs = []int32{
-	0, // 0: mangokit.default_code:extendee -> google.protobuf.EnumOptions
-	1, // 1: mangokit.code:extendee -> google.protobuf.EnumValueOptions
-	1, // 2: mangokit.desc:extendee -> google.protobuf.EnumValueOptions
-	3, // [3:3] is the sub-list for method output_type
-	3, // [3:3] is the sub-list for method input_type
-	3, // [3:3] is the sub-list for extension type_name
-	0, // [0:3] is the sub-list for extension extendee
+	1, // 0: errors.code_range:extendee -> google.protobuf.FileOptions
+	2, // 1: errors.default_code:extendee -> google.protobuf.EnumOptions
+	2, // 2: errors.enum_code_range:extendee -> google.protobuf.EnumOptions
+	3, // 3: errors.code:extendee -> google.protobuf.EnumValueOptions
+	3, // 4: errors.desc:extendee -> google.protobuf.EnumValueOptions
+	3, // 5: errors.biz_code:extendee -> google.protobuf.EnumValueOptions
+	0, // 6: errors.code_range:type_name -> errors.CodeRange
+	0, // 7: errors.enum_code_range:type_name -> errors.CodeRange
+	8, // [8:8] is the sub-list for method output_type
+	8, // [8:8] is the sub-list for method input_type
+	6, // [6:8] is the sub-list for extension type_name
+	0, // [0:6] is the sub-list for extension extendee
 	0, // [0:0] is the sub-list for field type_name
 }
 
@@ -106,18 +234,33 @@ func file_third_party_errors_errors_proto_init() {
 	if File_third_party_errors_errors_proto != nil {
 		return
 	}
+	if !protoimpl.UnsafeEnabled {
+		file_third_party_errors_errors_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CodeRange); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_third_party_errors_errors_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   0,
-			NumExtensions: 3,
+			NumMessages:   1,
+			NumExtensions: 6,
 			NumServices:   0,
 		},
 		GoTypes:           file_third_party_errors_errors_proto_goTypes,
 		DependencyIndexes: file_third_party_errors_errors_proto_depIdxs,
+		MessageInfos:      file_third_party_errors_errors_proto_msgTypes,
 		ExtensionInfos:    file_third_party_errors_errors_proto_extTypes,
 	}.Build()
 	File_third_party_errors_errors_proto = out.File
@@ -0,0 +1,183 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIs(t *testing.T) {
+	notFound := NotFound(1001, "USER_NOT_FOUND", "user not found")
+
+	tests := []struct {
+		err    error
+		target error
+		expect bool
+	}{
+		{notFound, NotFound(1001, "USER_NOT_FOUND", "another message"), true},
+		{fmt.Errorf("query user: %w", notFound), notFound, true},
+		{fmt.Errorf("outer: %w", fmt.Errorf("inner: %w", notFound)), notFound, true},
+		{Wrap(1002, http.StatusBadRequest, "BAD", "bad", notFound), notFound, true},
+		{notFound, NotFound(1002, "USER_NOT_FOUND", "user not found"), false},
+		{notFound, NotFound(1001, "OTHER", "user not found"), false},
+		{notFound, io.EOF, false},
+		{Wrap(1002, http.StatusBadRequest, "BAD", "bad", io.EOF), io.EOF, true},
+	}
+	for i, tt := range tests {
+		if got := Is(tt.err, tt.target); got != tt.expect {
+			t.Errorf("%d: Is(%v, %v) = %v, want %v", i, tt.err, tt.target, got, tt.expect)
+		}
+	}
+
+	var e Error
+	if !As(fmt.Errorf("wrap: %w", notFound), &e) || e.Reason() != "USER_NOT_FOUND" {
+		t.Errorf("As failed, got %v", e)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	if Convert(nil) != nil {
+		t.Error("Convert(nil) should be nil")
+	}
+
+	notFound := NotFound(1001, "USER_NOT_FOUND", "user not found")
+	if e := Convert(fmt.Errorf("wrap: %w", notFound)); e != notFound {
+		t.Errorf("expect wrapped error, got %v", e)
+	}
+
+	e := Convert(io.EOF)
+	if e.Code() != UnknownCode || e.Reason() != UnknownReason || e.HttpStatus() != DefaultStatus || e.Unwrap() != io.EOF {
+		t.Errorf("unexpected unknown error %v", e)
+	}
+
+	if !IsError(fmt.Errorf("wrap: %w", notFound)) || IsError(io.EOF) {
+		t.Error("IsError should unwrap")
+	}
+	if e := FromError(1001, http.StatusNotFound, "USER_NOT_FOUND", "user not found", io.EOF); !Is(e, notFound) || e.Unwrap() != io.EOF {
+		t.Errorf("unexpected deprecated FromError result %v", e)
+	}
+}
+
+func TestNewf(t *testing.T) {
+	e := Newf(1001, http.StatusNotFound, "USER_NOT_FOUND", "user %d not found", 42)
+	if e.Message() != "user 42 not found" || e.HttpStatus() != http.StatusNotFound {
+		t.Errorf("unexpected error %v", e)
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	sentinel := NotFound(1001, "USER_NOT_FOUND", "user not found")
+
+	e := Clone(sentinel).WithMetadata("id", "42").WithMetadata("name", "mango").WithMessagef("user %d not found", 42).WithCause(io.EOF)
+	if e.Message() != "user 42 not found" || e.Unwrap() != io.EOF || len(e.Metadata()) != 2 || e.Metadata()["id"] != "42" {
+		t.Errorf("unexpected error %v", e)
+	}
+	if !Is(e, sentinel) || !Is(e, io.EOF) {
+		t.Errorf("builder should keep code and reason, %v", e)
+	}
+
+	// 共享的错误变量不会被修改
+	if sentinel.Metadata() != nil || sentinel.Message() != "user not found" || sentinel.Unwrap() != nil {
+		t.Errorf("sentinel modified: %v", sentinel)
+	}
+	e2 := e.WithMetadata("id", "43")
+	if e.Metadata()["id"] != "42" || e2.Metadata()["id"] != "43" {
+		t.Errorf("metadata shared between copies")
+	}
+
+	c := Clone(sentinel)
+	c.Message_ = "changed"
+	if sentinel.Message() != "user not found" {
+		t.Errorf("clone shares fields with the original")
+	}
+
+	// 其他Error的实现同样可以复制
+	custom := customError{code: 1002, reason: "CUSTOM"}
+	if e := Clone(custom).WithMetadata("id", "1"); e.Code() != 1002 || e.Reason() != "CUSTOM" ||
+		e.HttpStatus() != http.StatusConflict || e.Metadata()["id"] != "1" || custom.Metadata() != nil {
+		t.Errorf("unexpected clone %v", e)
+	}
+	if Clone(nil) != nil {
+		t.Error("clone of nil should be nil")
+	}
+}
+
+// customError 包外的Error实现, 只需要实现Error接口中的方法
+type customError struct {
+	code   int32
+	reason string
+}
+
+var _ Error = customError{}
+
+func (e customError) Error() string               { return e.reason }
+func (e customError) Code() int32                 { return e.code }
+func (e customError) HttpStatus() int32           { return http.StatusConflict }
+func (e customError) Reason() string              { return e.reason }
+func (e customError) Message() string             { return "custom" }
+func (e customError) Metadata() map[string]string { return nil }
+func (e customError) Unwrap() error               { return nil }
+
+func TestStack(t *testing.T) {
+	if s := New(1, 500, "A", "a").(*ErrorImpl).Stack(); s != "" {
+		t.Errorf("stack should be disabled by default, got %s", s)
+	}
+
+	EnableStack(true)
+	defer EnableStack(false)
+
+	e := InternalServer(1, "A", "a").(*ErrorImpl)
+	if s := e.Stack(); !strings.Contains(s, "errors.TestStack") || strings.Contains(s, "errors.New\n") {
+		t.Errorf("unexpected stack %s", s)
+	}
+	if s := fmt.Sprintf("%+v", e); !strings.HasPrefix(s, e.Error()+"\n") || !strings.Contains(s, "errors_test.go") {
+		t.Errorf("unexpected format %s", s)
+	}
+	for _, verb := range []string{"%v", "%s", "%d"} {
+		if s := fmt.Sprintf(verb, e); s != e.Error() {
+			t.Errorf("%s: unexpected format %s", verb, s)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	notFound := NotFound(91001, "REGISTRY_USER_NOT_FOUND", "user not found")
+	exists := BadRequest(91002, "REGISTRY_USER_EXISTS", "user exists")
+	Register(exists, notFound, NotFound(91001, "REGISTRY_USER_NOT_FOUND", "duplicate registration"))
+
+	if e, ok := Lookup(91001); !ok || e != notFound {
+		t.Errorf("unexpected lookup result %v", e)
+	}
+	if e, ok := LookupReason("REGISTRY_USER_EXISTS"); !ok || e != exists {
+		t.Errorf("unexpected lookup result %v", e)
+	}
+	if _, ok := Lookup(91003); ok {
+		t.Error("unregistered code found")
+	}
+
+	// 不同package中的错误使用相同的错误码
+	other := BadRequest(91001, "REGISTRY_OTHER", "other package")
+	Register(other)
+	if e, ok := Lookup(91001); ok {
+		t.Errorf("ambiguous code should not be found, got %v", e)
+	}
+	if e, ok := LookupError("REGISTRY_OTHER", 91001); !ok || e != other {
+		t.Errorf("unexpected lookup result %v", e)
+	}
+	if errs := Conflicts()[91001]; len(errs) != 2 || errs[0] != notFound || errs[1] != other {
+		t.Errorf("unexpected conflicts %v", errs)
+	}
+
+	var reasons []string
+	for _, e := range Registered() {
+		if e.Code() >= 91000 && e.Code() < 92000 {
+			reasons = append(reasons, e.Reason())
+		}
+	}
+	if !reflect.DeepEqual(reasons, []string{"REGISTRY_OTHER", "REGISTRY_USER_NOT_FOUND", "REGISTRY_USER_EXISTS"}) {
+		t.Errorf("unexpected registered errors %v", reasons)
+	}
+}
@@ -0,0 +1,108 @@
+package errors
+
+import (
+	"sort"
+	"sync"
+)
+
+type registryKey struct {
+	reason string
+	code   int32
+}
+
+var registry = struct {
+	sync.RWMutex
+	errors  map[registryKey]Error
+	codes   map[int32][]Error
+	reasons map[string][]Error
+}{
+	errors:  make(map[registryKey]Error),
+	codes:   make(map[int32][]Error),
+	reasons: make(map[string][]Error),
+}
+
+// Register 注册错误, 由protoc-gen-go-error生成的代码在init中调用, 之后可以通过Lookup根据错误码查找错误
+// 错误使用reason和code作为key, 同一个错误重复注册时保留先注册的错误
+// 不同package中的错误可能使用相同的错误码或reason, 此时Lookup和LookupReason不会返回其中任意一个, 需要使用LookupError
+func Register(errs ...Error) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	for _, e := range errs {
+		key := registryKey{reason: e.Reason(), code: e.Code()}
+		if _, ok := registry.errors[key]; ok {
+			continue
+		}
+		registry.errors[key] = e
+		registry.codes[key.code] = append(registry.codes[key.code], e)
+		registry.reasons[key.reason] = append(registry.reasons[key.reason], e)
+	}
+}
+
+// Lookup 根据错误码查找已注册的错误, 返回的错误是共享的, 需要修改时使用WithMetadata等方法
+// 多个错误使用相同的错误码时返回false
+func Lookup(code int32) (Error, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	return unique(registry.codes[code])
+}
+
+// LookupReason 根据reason查找已注册的错误, 多个错误使用相同的reason时返回false
+func LookupReason(reason string) (Error, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	return unique(registry.reasons[reason])
+}
+
+// LookupError 根据reason和错误码查找已注册的错误
+func LookupError(reason string, code int32) (Error, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	e, ok := registry.errors[registryKey{reason: reason, code: code}]
+	return e, ok
+}
+
+// Conflicts 返回被多个错误使用的错误码, 用于在启动时检查不同package的错误码是否冲突
+func Conflicts() map[int32][]Error {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	conflicts := make(map[int32][]Error)
+	for code, errs := range registry.codes {
+		if len(errs) > 1 {
+			conflicts[code] = append([]Error(nil), errs...)
+		}
+	}
+
+	return conflicts
+}
+
+// Registered 返回所有已注册的错误, 按照错误码和reason排序
+func Registered() []Error {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	errs := make([]Error, 0, len(registry.errors))
+	for _, e := range registry.errors {
+		errs = append(errs, e)
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		if errs[i].Code() != errs[j].Code() {
+			return errs[i].Code() < errs[j].Code()
+		}
+		return errs[i].Reason() < errs[j].Reason()
+	})
+
+	return errs
+}
+
+func unique(errs []Error) (Error, bool) {
+	if len(errs) != 1 {
+		return nil, false
+	}
+
+	return errs[0], true
+}
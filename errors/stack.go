@@ -0,0 +1,76 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	maxStackDepth = 32
+	pkgPath       = "github.com/mangohow/mangokit/errors"
+)
+
+var stackEnabled atomic.Bool
+
+// EnableStack 开启后创建错误时记录调用栈, 使用 %+v 格式化错误时输出, 仅用于内部日志, 不会返回给调用方
+// 记录调用栈有一定开销, 默认关闭
+func EnableStack(enable bool) {
+	stackEnabled.Store(enable)
+}
+
+type stack []uintptr
+
+func callers() stack {
+	if !stackEnabled.Load() {
+		return nil
+	}
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(3, pcs)
+
+	return pcs[:n]
+}
+
+// String 格式化调用栈, 跳过栈顶errors包内部的调用, 例如BadRequest, WithMetadata等
+func (s stack) String() string {
+	if len(s) == 0 {
+		return ""
+	}
+	builder := &strings.Builder{}
+	frames := runtime.CallersFrames(s)
+	skip := true
+	for {
+		frame, more := frames.Next()
+		if skip && (!strings.HasPrefix(frame.Function, pkgPath+".") || strings.HasSuffix(frame.File, "_test.go")) {
+			skip = false
+		}
+		if !skip {
+			fmt.Fprintf(builder, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		}
+		if !more {
+			break
+		}
+	}
+
+	return builder.String()
+}
+
+// Format 实现fmt.Formatter, %+v 输出错误信息以及调用栈
+func (e *ErrorImpl) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		io.WriteString(s, e.Error())
+		if s.Flag('+') && len(e.stack) > 0 {
+			io.WriteString(s, "\n")
+			io.WriteString(s, e.stack.String())
+		}
+	case 's':
+		io.WriteString(s, e.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	default:
+		fmt.Fprint(s, e.Error())
+	}
+}
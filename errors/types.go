@@ -7,7 +7,7 @@ func BadRequest(code int32, reason, message string) Error {
 }
 
 func BadRequestCause(code int32, reason, message string, err error) Error {
-	return FromError(code, http.StatusBadRequest, reason, message, err)
+	return Wrap(code, http.StatusBadRequest, reason, message, err)
 }
 
 func Unauthorized(code int32, reason, message string) Error {
@@ -15,7 +15,7 @@ func Unauthorized(code int32, reason, message string) Error {
 }
 
 func UnauthorizedCause(code int32, reason, message string, err error) Error {
-	return FromError(code, http.StatusUnauthorized, reason, message, err)
+	return Wrap(code, http.StatusUnauthorized, reason, message, err)
 }
 
 func Forbidden(code int32, reason, message string) Error {
@@ -23,7 +23,7 @@ func Forbidden(code int32, reason, message string) Error {
 }
 
 func ForbiddenCause(code int32, reason, message string, err error) Error {
-	return FromError(code, http.StatusForbidden, reason, message, err)
+	return Wrap(code, http.StatusForbidden, reason, message, err)
 }
 
 func NotFound(code int32, reason, message string) Error {
@@ -31,7 +31,15 @@ func NotFound(code int32, reason, message string) Error {
 }
 
 func NotFoundCause(code int32, reason, message string, err error) Error {
-	return FromError(code, http.StatusNotFound, reason, message, err)
+	return Wrap(code, http.StatusNotFound, reason, message, err)
+}
+
+func TooManyRequests(code int32, reason, message string) Error {
+	return New(code, http.StatusTooManyRequests, reason, message)
+}
+
+func TooManyRequestsCause(code int32, reason, message string, err error) Error {
+	return Wrap(code, http.StatusTooManyRequests, reason, message, err)
 }
 
 func InternalServer(code int32, reason, message string) Error {
@@ -39,7 +47,7 @@ func InternalServer(code int32, reason, message string) Error {
 }
 
 func InternalServerCause(code int32, reason, message string, err error) Error {
-	return FromError(code, http.StatusInternalServerError, reason, message, err)
+	return Wrap(code, http.StatusInternalServerError, reason, message, err)
 }
 
 func ServiceUnavailable(code int32, reason, message string) Error {
@@ -47,5 +55,5 @@ func ServiceUnavailable(code int32, reason, message string) Error {
 }
 
 func ServiceUnavailableCause(code int32, reason, message string, err error) Error {
-	return FromError(code, http.StatusServiceUnavailable, reason, message, err)
+	return Wrap(code, http.StatusServiceUnavailable, reason, message, err)
 }
@@ -0,0 +1,213 @@
+package metrics
+
+import (
+	"bufio"
+	"math"
+	"sort"
+	"sync"
+)
+
+// DefBuckets 默认的直方图桶, 单位为秒
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Counter 只增不减的计数器
+type Counter struct {
+	mu    sync.Mutex
+	value float64
+}
+
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add 增加v, v必须大于等于0
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	c.mu.Lock()
+	c.value += v
+	c.mu.Unlock()
+}
+
+func (c *Counter) Value() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.value
+}
+
+// Gauge 可增可减的仪表盘
+type Gauge struct {
+	mu    sync.Mutex
+	value float64
+}
+
+func (g *Gauge) Set(v float64) {
+	g.mu.Lock()
+	g.value = v
+	g.mu.Unlock()
+}
+
+func (g *Gauge) Inc() {
+	g.Add(1)
+}
+
+func (g *Gauge) Dec() {
+	g.Add(-1)
+}
+
+func (g *Gauge) Add(v float64) {
+	g.mu.Lock()
+	g.value += v
+	g.mu.Unlock()
+}
+
+func (g *Gauge) Value() float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.value
+}
+
+// Histogram 直方图, 统计观测值落在每个桶中的数量
+type Histogram struct {
+	buckets []float64
+
+	mu     sync.Mutex
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+	h.mu.Lock()
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.sum += v
+	h.count++
+	h.mu.Unlock()
+}
+
+// Count 观测值的数量
+func (h *Histogram) Count() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.count
+}
+
+// Sum 观测值的总和
+func (h *Histogram) Sum() float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.sum
+}
+
+// CounterVec 按照label区分的一组Counter
+type CounterVec struct {
+	*vec[Counter]
+}
+
+// NewCounterVec 注册CounterVec, 同名的CounterVec已存在时返回已存在的
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	d := &desc{name: name, help: help, typ: typeCounter, labels: labels}
+	return r.register(d, func() collector {
+		return &CounterVec{newVec(d, func() Counter { return Counter{} })}
+	}).(*CounterVec)
+}
+
+// With 获取label值对应的Counter, labelValues的顺序与注册时的labels一致
+func (v *CounterVec) With(labelValues ...string) *Counter {
+	return v.with(labelValues...)
+}
+
+func (v *CounterVec) desc() *desc {
+	return v.d
+}
+
+func (v *CounterVec) write(w *bufio.Writer) {
+	v.d.writeHeader(w)
+	v.each(func(values []string, c *Counter) {
+		writeSample(w, v.d.name, v.d.labels, values, "", "", c.Value())
+	})
+}
+
+// GaugeVec 按照label区分的一组Gauge
+type GaugeVec struct {
+	*vec[Gauge]
+}
+
+// NewGaugeVec 注册GaugeVec, 同名的GaugeVec已存在时返回已存在的
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	d := &desc{name: name, help: help, typ: typeGauge, labels: labels}
+	return r.register(d, func() collector {
+		return &GaugeVec{newVec(d, func() Gauge { return Gauge{} })}
+	}).(*GaugeVec)
+}
+
+func (v *GaugeVec) With(labelValues ...string) *Gauge {
+	return v.with(labelValues...)
+}
+
+func (v *GaugeVec) desc() *desc {
+	return v.d
+}
+
+func (v *GaugeVec) write(w *bufio.Writer) {
+	v.d.writeHeader(w)
+	v.each(func(values []string, g *Gauge) {
+		writeSample(w, v.d.name, v.d.labels, values, "", "", g.Value())
+	})
+}
+
+// HistogramVec 按照label区分的一组Histogram
+type HistogramVec struct {
+	*vec[Histogram]
+	buckets []float64
+}
+
+// NewHistogramVec 注册HistogramVec, buckets为空时使用DefBuckets
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	d := &desc{name: name, help: help, typ: typeHistogram, labels: labels}
+	return r.register(d, func() collector {
+		return &HistogramVec{
+			vec: newVec(d, func() Histogram {
+				return Histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+			}),
+			buckets: buckets,
+		}
+	}).(*HistogramVec)
+}
+
+func (v *HistogramVec) With(labelValues ...string) *Histogram {
+	return v.with(labelValues...)
+}
+
+func (v *HistogramVec) desc() *desc {
+	return v.d
+}
+
+func (v *HistogramVec) write(w *bufio.Writer) {
+	v.d.writeHeader(w)
+	v.each(func(values []string, h *Histogram) {
+		h.mu.Lock()
+		counts := append([]uint64(nil), h.counts...)
+		sum, count := h.sum, h.count
+		h.mu.Unlock()
+
+		var cumulative uint64
+		for i, upper := range v.buckets {
+			cumulative += counts[i]
+			writeSample(w, v.d.name+"_bucket", v.d.labels, values, "le", formatFloat(upper), float64(cumulative))
+		}
+		writeSample(w, v.d.name+"_bucket", v.d.labels, values, "le", formatFloat(math.Inf(1)), float64(count))
+		writeSample(w, v.d.name+"_sum", v.d.labels, values, "", "", sum)
+		writeSample(w, v.d.name+"_count", v.d.labels, values, "", "", float64(count))
+	})
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistryWriteTo(t *testing.T) {
+	reg := NewRegistry()
+	requests := reg.NewCounterVec("requests_total", "Total requests.", "method", "path")
+	requests.With("GET", "/user").Inc()
+	requests.With("GET", "/user").Add(2)
+	requests.With("POST", `/a"b`).Inc()
+
+	inflight := reg.NewGaugeVec("in_flight", "In flight requests.")
+	inflight.With().Inc()
+	inflight.With().Inc()
+	inflight.With().Dec()
+
+	latency := reg.NewHistogramVec("latency_seconds", "Latency.", []float64{0.1, 1}, "method")
+	latency.With("GET").Observe(0.05)
+	latency.With("GET").Observe(0.5)
+	latency.With("GET").Observe(5)
+
+	if same := reg.NewCounterVec("requests_total", "Total requests.", "method", "path"); same != requests {
+		t.Error("registering the same counter should return the existing one")
+	}
+
+	var sb strings.Builder
+	if _, err := reg.WriteTo(&sb); err != nil {
+		t.Fatal(err)
+	}
+	expect := `# HELP in_flight In flight requests.
+# TYPE in_flight gauge
+in_flight 1
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{method="GET",le="0.1"} 1
+latency_seconds_bucket{method="GET",le="1"} 2
+latency_seconds_bucket{method="GET",le="+Inf"} 3
+latency_seconds_sum{method="GET"} 5.55
+latency_seconds_count{method="GET"} 3
+# HELP requests_total Total requests.
+# TYPE requests_total counter
+requests_total{method="GET",path="/user"} 3
+requests_total{method="POST",path="/a\"b"} 1
+`
+	if sb.String() != expect {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", sb.String(), expect)
+	}
+}
+
+func TestRegisterConflict(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewCounterVec("conflict", "", "a")
+	defer func() {
+		if recover() == nil {
+			t.Error("expect panic when registering with different type")
+		}
+	}()
+	reg.NewGaugeVec("conflict", "", "a")
+}
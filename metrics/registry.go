@@ -0,0 +1,233 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+
+	// ContentType prometheus文本格式的Content-Type
+	ContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// DefaultRegistry 默认的Registry
+var DefaultRegistry = NewRegistry()
+
+type collector interface {
+	desc() *desc
+	write(w *bufio.Writer)
+}
+
+// Registry 指标注册中心, 可以按照prometheus文本格式输出所有指标
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]collector
+	names      []string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		collectors: make(map[string]collector),
+	}
+}
+
+// register 注册指标, 同名同类型同label的指标已存在时返回已存在的指标
+func (r *Registry) register(d *desc, newFn func() collector) collector {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if c, ok := r.collectors[d.name]; ok {
+		if !c.desc().equal(d) {
+			panic(fmt.Sprintf("metrics: %s already registered with different type or labels", d.name))
+		}
+		return c
+	}
+
+	c := newFn()
+	r.collectors[d.name] = c
+	r.names = append(r.names, d.name)
+	sort.Strings(r.names)
+
+	return c
+}
+
+// WriteTo 按照prometheus文本格式输出所有指标
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+
+	r.mu.RLock()
+	for _, name := range r.names {
+		r.collectors[name].write(bw)
+	}
+	r.mu.RUnlock()
+
+	err := bw.Flush()
+
+	return cw.n, err
+}
+
+// Handler 返回输出指标的http.Handler, 可以注册到 /metrics 路由供prometheus抓取
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		_, _ = r.WriteTo(w)
+	})
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type desc struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+}
+
+func (d *desc) equal(o *desc) bool {
+	if d.typ != o.typ || len(d.labels) != len(o.labels) {
+		return false
+	}
+	for i := range d.labels {
+		if d.labels[i] != o.labels[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (d *desc) writeHeader(w *bufio.Writer) {
+	if d.help != "" {
+		w.WriteString("# HELP " + d.name + " " + escapeHelp(d.help) + "\n")
+	}
+	w.WriteString("# TYPE " + d.name + " " + d.typ + "\n")
+}
+
+// vec 按照label值保存指标
+type vec[T any] struct {
+	d      *desc
+	newFn  func() T
+	mu     sync.RWMutex
+	series map[string]*T
+	values map[string][]string
+}
+
+func newVec[T any](d *desc, newFn func() T) *vec[T] {
+	return &vec[T]{
+		d:      d,
+		newFn:  newFn,
+		series: make(map[string]*T),
+		values: make(map[string][]string),
+	}
+}
+
+func (v *vec[T]) with(labelValues ...string) *T {
+	if len(labelValues) != len(v.d.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.d.name, len(v.d.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	v.mu.RLock()
+	s, ok := v.series[key]
+	v.mu.RUnlock()
+	if ok {
+		return s
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if s, ok = v.series[key]; ok {
+		return s
+	}
+	t := v.newFn()
+	s = &t
+	v.series[key] = s
+	v.values[key] = append([]string(nil), labelValues...)
+
+	return s
+}
+
+// each 按照label值的顺序遍历所有指标, 保证输出稳定
+func (v *vec[T]) each(fn func(labelValues []string, s *T)) {
+	v.mu.RLock()
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	v.mu.RUnlock()
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v.mu.RLock()
+		s, values := v.series[k], v.values[k]
+		v.mu.RUnlock()
+		fn(values, s)
+	}
+}
+
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraLabel, extraValue string, value float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraLabel != "" {
+		w.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(l + `="` + escapeLabel(values[i]) + `"`)
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extraLabel + `="` + extraValue + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case v > 0 && v*0 != 0:
+		return "+Inf"
+	case v < 0 && v*0 != 0:
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string {
+	return labelReplacer.Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return helpReplacer.Replace(s)
+}
@@ -0,0 +1,49 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package proc
+
+import (
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// NewCPUSampler 返回进程CPU使用率的采样函数, 结果为两次调用之间的平均使用率, 按照CPU核数归一化到[0, 1]
+func NewCPUSampler() func() float64 {
+	var (
+		mu        sync.Mutex
+		lastCPU   = cpuTime()
+		lastWall  = time.Now()
+		lastUsage float64
+	)
+
+	return func() float64 {
+		mu.Lock()
+		defer mu.Unlock()
+
+		now, cpu := time.Now(), cpuTime()
+		wall := now.Sub(lastWall)
+		if wall < 10*time.Millisecond {
+			return lastUsage
+		}
+		usage := float64(cpu-lastCPU) / float64(wall) / float64(runtime.NumCPU())
+		if usage > 1 {
+			usage = 1
+		}
+		lastCPU, lastWall, lastUsage = cpu, now, usage
+
+		return usage
+	}
+}
+
+// cpuTime 进程使用的用户态和内核态CPU时间
+func cpuTime() time.Duration {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0
+	}
+
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+}
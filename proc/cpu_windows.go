@@ -0,0 +1,11 @@
+//go:build windows
+// +build windows
+
+package proc
+
+// NewCPUSampler windows下暂不支持采样CPU使用率, 始终返回0
+func NewCPUSampler() func() float64 {
+	return func() float64 {
+		return 0
+	}
+}
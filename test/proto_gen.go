@@ -5,14 +5,6 @@ import (
 	"github.com/mangohow/mangokit/transport/http"
 )
 
-type GreeterHTTPService interface {
-	SayHello(ctx context.Context, req *HelloRequest) (*HelloResponse, error)
-	SayHello1(ctx context.Context, req *HelloRequest) error
-	SayHello2(ctx context.Context) (*HelloResponse, error)
-	SayHello3(ctx context.Context) error
-	Test(ctx context.Context, req *Kinds) error
-}
-
 type HelloRequest struct {
 	Name string `json:"name" form:"name" param:"name"`
 }
@@ -30,26 +22,16 @@ type Kinds struct {
 	Kboolp   *bool   `json:"kboolp" param:"kboolp"`
 }
 
-func RegisterGreeterHTTPService(server *http.Server, svc GreeterHTTPService) {
-	server.RegisterService(_GreeterHTTPService_serviceDesc, svc)
+type GreeterHTTPService interface {
+	SayHello(context.Context, *HelloRequest) (*HelloResponse, error)
+	SayHello1(context.Context, *HelloRequest) error
+	SayHello2(context.Context) (*HelloResponse, error)
+	SayHello3(context.Context) error
+	Test(context.Context, *Kinds) error
 }
 
-func _Greeter_Test_HTTP_Handler(svc interface{}, ctx context.Context, dec func(interface{}) error, middleware http.Middleware) (interface{}, error) {
-	in := new(Kinds)
-	err := dec(in)
-	if err != nil {
-		return nil, err
-	}
-
-	if middleware == nil {
-		return nil, svc.(GreeterHTTPService).Test(ctx, in)
-	}
-
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return nil, svc.(GreeterHTTPService).Test(ctx, in)
-	}
-
-	return middleware(ctx, in, handler)
+func RegisterGreeterHTTPService(server *http.Server, svc GreeterHTTPService, opts ...http.RegisterOption) {
+	server.RegisterService(_GreeterHTTPService_serviceDesc, svc, opts...)
 }
 
 func _Greeter_SayHello_HTTP_Handler(svc interface{}, ctx context.Context, dec func(interface{}) error, middleware http.Middleware) (interface{}, error) {
@@ -89,12 +71,6 @@ func _Greeter_SayHello1_HTTP_Handler(svc interface{}, ctx context.Context, dec f
 }
 
 func _Greeter_SayHello2_HTTP_Handler(svc interface{}, ctx context.Context, dec func(interface{}) error, middleware http.Middleware) (interface{}, error) {
-	in := new(HelloRequest)
-	err := dec(in)
-	if err != nil {
-		return nil, err
-	}
-
 	if middleware == nil {
 		return svc.(GreeterHTTPService).SayHello2(ctx)
 	}
@@ -103,33 +79,45 @@ func _Greeter_SayHello2_HTTP_Handler(svc interface{}, ctx context.Context, dec f
 		return svc.(GreeterHTTPService).SayHello2(ctx)
 	}
 
-	return middleware(ctx, in, handler)
+	return middleware(ctx, nil, handler)
 }
 
 func _Greeter_SayHello3_HTTP_Handler(svc interface{}, ctx context.Context, dec func(interface{}) error, middleware http.Middleware) (interface{}, error) {
-	in := new(HelloRequest)
+	if middleware == nil {
+		return nil, svc.(GreeterHTTPService).SayHello3(ctx)
+	}
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, svc.(GreeterHTTPService).SayHello3(ctx)
+	}
+
+	return middleware(ctx, nil, handler)
+}
+
+func _Greeter_Test_HTTP_Handler(svc interface{}, ctx context.Context, dec func(interface{}) error, middleware http.Middleware) (interface{}, error) {
+	in := new(Kinds)
 	err := dec(in)
 	if err != nil {
 		return nil, err
 	}
 
 	if middleware == nil {
-		return nil, svc.(GreeterHTTPService).SayHello3(ctx)
+		return nil, svc.(GreeterHTTPService).Test(ctx, in)
 	}
 
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return nil, svc.(GreeterHTTPService).SayHello3(ctx)
+		return nil, svc.(GreeterHTTPService).Test(ctx, in)
 	}
 
 	return middleware(ctx, in, handler)
 }
 
 type GreeterHTTPClient interface {
-	SayHello(ctx context.Context, request *HelloRequest, opts ...http.CallOption) (*HelloResponse, error)
-	SayHello1(ctx context.Context, request *HelloRequest, opts ...http.CallOption) error
+	SayHello(ctx context.Context, req *HelloRequest, opts ...http.CallOption) (*HelloResponse, error)
+	SayHello1(ctx context.Context, req *HelloRequest, opts ...http.CallOption) error
 	SayHello2(ctx context.Context, opts ...http.CallOption) (*HelloResponse, error)
 	SayHello3(ctx context.Context, opts ...http.CallOption) error
-	Test(ctx context.Context, request *Kinds, opts ...http.CallOption) error
+	Test(ctx context.Context, req *Kinds, opts ...http.CallOption) error
 }
 
 type greeterHTTPClient struct {
@@ -144,64 +132,87 @@ func (c *greeterHTTPClient) SayHello(ctx context.Context, req *HelloRequest, opt
 	reply := new(HelloResponse)
 	pattern := "/helloworld/:name"
 	path := http.EncodeURL(pattern, req, true)
+	opts = append([]http.CallOption{http.OperationCallOption("/test.Greeter/SayHello"), http.PathTemplateCallOption("/helloworld/:name")}, opts...)
 	_, err := c.cc.Invoke(ctx, "GET", path, req, reply, opts...)
-	return reply, err
-}
 
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
 func (c *greeterHTTPClient) SayHello1(ctx context.Context, req *HelloRequest, opts ...http.CallOption) error {
 	pattern := "/helloworld1"
 	path := http.EncodeURLFromForm(pattern, req)
+	opts = append([]http.CallOption{http.OperationCallOption("/test.Greeter/SayHello1"), http.PathTemplateCallOption("/helloworld1")}, opts...)
 	_, err := c.cc.Invoke(ctx, "GET", path, req, nil, opts...)
+
 	return err
 }
-
 func (c *greeterHTTPClient) SayHello2(ctx context.Context, opts ...http.CallOption) (*HelloResponse, error) {
 	reply := new(HelloResponse)
 	path := "/helloworld2"
+	opts = append([]http.CallOption{http.OperationCallOption("/test.Greeter/SayHello2"), http.PathTemplateCallOption("/helloworld2")}, opts...)
 	_, err := c.cc.Invoke(ctx, "GET", path, nil, reply, opts...)
-	return reply, err
-}
 
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
 func (c *greeterHTTPClient) SayHello3(ctx context.Context, opts ...http.CallOption) error {
-	path := "/helloworld3"
+	path := "/helloworld3/"
+	opts = append([]http.CallOption{http.OperationCallOption("/test.Greeter/SayHello3"), http.PathTemplateCallOption("/helloworld3/")}, opts...)
 	_, err := c.cc.Invoke(ctx, "GET", path, nil, nil, opts...)
+
 	return err
 }
-
 func (c *greeterHTTPClient) Test(ctx context.Context, req *Kinds, opts ...http.CallOption) error {
 	pattern := "/test/:kint/:kintp/:kstring/:kstringp/:kbool/:kboolp"
 	path := http.EncodeURL(pattern, req, false)
+	opts = append([]http.CallOption{http.OperationCallOption("/test.Greeter/Test"), http.PathTemplateCallOption("/test/:kint/:kintp/:kstring/:kstringp/:kbool/:kboolp")}, opts...)
 	_, err := c.cc.Invoke(ctx, "GET", path, req, nil, opts...)
+
 	return err
 }
 
 var _GreeterHTTPService_serviceDesc = &http.ServiceDesc{
+	ServiceName: "Greeter",
 	HandlerType: (*GreeterHTTPService)(nil),
 	Methods: []http.MethodDesc{
 		{
-			Method:  "GET",
-			Path:    "/helloworld/:name",
-			Handler: _Greeter_SayHello_HTTP_Handler,
+			Name:       "SayHello",
+			FullMethod: "/test.Greeter/SayHello",
+			Method:     "GET",
+			Path:       "/helloworld/:name",
+			Handler:    _Greeter_SayHello_HTTP_Handler,
 		},
 		{
-			Method:  "GET",
-			Path:    "/helloworld1",
-			Handler: _Greeter_SayHello1_HTTP_Handler,
+			Name:       "SayHello1",
+			FullMethod: "/test.Greeter/SayHello1",
+			Method:     "GET",
+			Path:       "/helloworld1",
+			Handler:    _Greeter_SayHello1_HTTP_Handler,
 		},
 		{
-			Method:  "GET",
-			Path:    "/helloworld2",
-			Handler: _Greeter_SayHello2_HTTP_Handler,
+			Name:       "SayHello2",
+			FullMethod: "/test.Greeter/SayHello2",
+			Method:     "GET",
+			Path:       "/helloworld2",
+			Handler:    _Greeter_SayHello2_HTTP_Handler,
 		},
 		{
-			Method:  "GET",
-			Path:    "/helloworld3/",
-			Handler: _Greeter_SayHello3_HTTP_Handler,
+			Name:       "SayHello3",
+			FullMethod: "/test.Greeter/SayHello3",
+			Method:     "GET",
+			Path:       "/helloworld3/",
+			Handler:    _Greeter_SayHello3_HTTP_Handler,
 		},
 		{
-			Method:  "GET",
-			Path:    "/test/:kint/:kintp/:kstring/:kstringp/:kbool/:kboolp",
-			Handler: _Greeter_Test_HTTP_Handler,
+			Name:       "Test",
+			FullMethod: "/test.Greeter/Test",
+			Method:     "GET",
+			Path:       "/test/:kint/:kintp/:kstring/:kstringp/:kbool/:kboolp",
+			Handler:    _Greeter_Test_HTTP_Handler,
 		},
 	},
 }
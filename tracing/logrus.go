@@ -0,0 +1,25 @@
+package tracing
+
+import "github.com/sirupsen/logrus"
+
+// LogrusHook 将context中的trace id和span id添加到日志中
+// 使用 log.AddHook(tracing.LogrusHook{}) 注册, 打印日志时使用 log.WithContext(ctx)
+type LogrusHook struct{}
+
+func (LogrusHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (LogrusHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	sc := SpanContextFromContext(entry.Context)
+	if !sc.IsValid() {
+		return nil
+	}
+	entry.Data["trace_id"] = sc.TraceID.String()
+	entry.Data["span_id"] = sc.SpanID.String()
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+
+	supportedVersion = 0
+)
+
+// Extract 从请求头中解析W3C traceparent和tracestate
+func Extract(header http.Header) (SpanContext, bool) {
+	sc, err := ParseTraceParent(header.Get(TraceParentHeader))
+	if err != nil {
+		return SpanContext{}, false
+	}
+	sc.TraceState = header.Get(TraceStateHeader)
+	sc.Remote = true
+
+	return sc, true
+}
+
+// Inject 将SpanContext写入请求头
+func Inject(sc SpanContext, header http.Header) {
+	if !sc.IsValid() {
+		return
+	}
+	header.Set(TraceParentHeader, FormatTraceParent(sc))
+	if sc.TraceState != "" {
+		header.Set(TraceStateHeader, sc.TraceState)
+	}
+}
+
+// FormatTraceParent 格式化为 version-traceid-parentid-flags
+func FormatTraceParent(sc SpanContext) string {
+	return fmt.Sprintf("%02x-%s-%s-%02x", supportedVersion, sc.TraceID, sc.SpanID, sc.Flags)
+}
+
+// ParseTraceParent 解析traceparent, 例如 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func ParseTraceParent(s string) (SpanContext, error) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 {
+		return sc, fmt.Errorf("invalid traceparent %q", s)
+	}
+
+	version, err := decodeHex(parts[0], 1)
+	if err != nil || version[0] == 0xff || (version[0] == supportedVersion && len(parts) != 4) {
+		return sc, fmt.Errorf("invalid traceparent version %q", parts[0])
+	}
+	traceID, err := decodeHex(parts[1], 16)
+	if err != nil {
+		return sc, fmt.Errorf("invalid trace id %q", parts[1])
+	}
+	spanID, err := decodeHex(parts[2], 8)
+	if err != nil {
+		return sc, fmt.Errorf("invalid parent id %q", parts[2])
+	}
+	flags, err := decodeHex(parts[3], 1)
+	if err != nil {
+		return sc, fmt.Errorf("invalid trace flags %q", parts[3])
+	}
+
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Flags = flags[0]
+	if !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("invalid traceparent %q", s)
+	}
+
+	return sc, nil
+}
+
+// decodeHex 解析长度为n字节的小写十六进制字符串
+func decodeHex(s string, n int) ([]byte, error) {
+	if len(s) != n*2 || strings.ToLower(s) != s {
+		return nil, fmt.Errorf("invalid hex %q", s)
+	}
+
+	return hex.DecodeString(s)
+}
@@ -0,0 +1,138 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// TraceID W3C trace-id, 16字节
+type TraceID [16]byte
+
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// SpanID W3C parent-id, 8字节
+type SpanID [8]byte
+
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// FlagsSampled trace-flags中的sampled标记
+const FlagsSampled byte = 0x01
+
+// SpanContext 在进程间传递的span信息
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      byte
+	TraceState string
+	Remote     bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+func (sc SpanContext) IsSampled() bool {
+	return sc.Flags&FlagsSampled == FlagsSampled
+}
+
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = iota
+	SpanKindServer
+	SpanKindClient
+)
+
+func (k SpanKind) String() string {
+	switch k {
+	case SpanKindServer:
+		return "server"
+	case SpanKindClient:
+		return "client"
+	default:
+		return "internal"
+	}
+}
+
+// SpanData span结束后交给Exporter的数据
+type SpanData struct {
+	Name         string
+	Kind         SpanKind
+	SpanContext  SpanContext
+	ParentSpanID SpanID
+	StartTime    time.Time
+	EndTime      time.Time
+	Attributes   map[string]string
+	Err          error
+}
+
+// Span 一次操作, 由Tracer.Start创建, 必须调用End结束
+type Span struct {
+	tracer *Tracer
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+func (s *Span) SpanContext() SpanContext {
+	return s.data.SpanContext
+}
+
+// SetAttribute 设置span的属性, 例如 http.method
+func (s *Span) SetAttribute(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Attributes == nil {
+		s.data.Attributes = make(map[string]string)
+	}
+	s.data.Attributes[key] = value
+}
+
+// RecordError 记录span的错误
+func (s *Span) RecordError(err error) {
+	s.mu.Lock()
+	s.data.Err = err
+	s.mu.Unlock()
+}
+
+// End 结束span, 采样的span会交给Exporter, 重复调用无效
+func (s *Span) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.EndTime = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	if data.SpanContext.IsSampled() && s.tracer.exporter != nil {
+		s.tracer.exporter.Export(data)
+	}
+}
+
+func newTraceID() (id TraceID) {
+	_, _ = rand.Read(id[:])
+	return
+}
+
+func newSpanID() (id SpanID) {
+	_, _ = rand.Read(id[:])
+	return
+}
@@ -0,0 +1,138 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Exporter 导出结束的span, 可以通过实现Exporter对接OpenTelemetry等系统
+type Exporter interface {
+	Export(span SpanData)
+}
+
+type Option func(t *Tracer)
+
+// WithExporter 设置span的导出器
+func WithExporter(exporter Exporter) Option {
+	return func(t *Tracer) {
+		t.exporter = exporter
+	}
+}
+
+// Tracer 创建span
+type Tracer struct {
+	exporter Exporter
+}
+
+func NewTracer(opts ...Option) *Tracer {
+	t := &Tracer{}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+// Start 创建名称为name的span, 并将span存入返回的context中
+// ctx中存在span或者远程传递的SpanContext时, 新的span作为它的子span, 否则创建新的trace
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	sc := SpanContext{
+		SpanID: newSpanID(),
+		Flags:  FlagsSampled,
+	}
+	var parent SpanID
+	if psc := SpanContextFromContext(ctx); psc.IsValid() {
+		sc.TraceID = psc.TraceID
+		sc.Flags = psc.Flags
+		sc.TraceState = psc.TraceState
+		parent = psc.SpanID
+	} else {
+		sc.TraceID = newTraceID()
+	}
+
+	span := &Span{
+		tracer: t,
+		data: SpanData{
+			Name:         name,
+			Kind:         kind,
+			SpanContext:  sc,
+			ParentSpanID: parent,
+			StartTime:    time.Now(),
+		},
+	}
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+type spanKey struct{}
+
+type remoteSpanContextKey struct{}
+
+// SpanFromContext 获取context中的span, 不存在时返回nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// ContextWithSpan 将span存入context, 例如将服务端span添加到请求的context中, 使日志中包含trace id
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// ContextWithRemoteSpanContext 将从请求头中解析的SpanContext存入context, 作为之后创建的span的父span
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	sc.Remote = true
+	return context.WithValue(ctx, remoteSpanContextKey{}, sc)
+}
+
+// SpanContextFromContext 获取context中当前span的SpanContext, 没有span时返回远程传递的SpanContext
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if ctx == nil {
+		return SpanContext{}
+	}
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext()
+	}
+	sc, _ := ctx.Value(remoteSpanContextKey{}).(SpanContext)
+
+	return sc
+}
+
+// TraceIDFromContext 获取context中的trace id, 不存在时返回空字符串
+func TraceIDFromContext(ctx context.Context) string {
+	if sc := SpanContextFromContext(ctx); sc.IsValid() {
+		return sc.TraceID.String()
+	}
+
+	return ""
+}
+
+// InMemoryExporter 将span保存在内存中, 用于测试
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+func (e *InMemoryExporter) Export(span SpanData) {
+	e.mu.Lock()
+	e.spans = append(e.spans, span)
+	e.mu.Unlock()
+}
+
+// Spans 返回所有已导出的span
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
+
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	e.spans = nil
+	e.mu.Unlock()
+}
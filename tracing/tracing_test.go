@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	tests := []struct {
+		in    string
+		valid bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		sc, err := ParseTraceParent(tt.in)
+		if (err == nil) != tt.valid {
+			t.Errorf("%q: valid = %v, err = %v", tt.in, tt.valid, err)
+			continue
+		}
+		if tt.valid && !strings.HasPrefix(tt.in[3:], sc.TraceID.String()+"-"+sc.SpanID.String()) {
+			t.Errorf("%q: unexpected span context %s", tt.in, FormatTraceParent(sc))
+		}
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	tracer := NewTracer(WithExporter(exporter))
+
+	ctx, client := tracer.Start(context.Background(), "client", SpanKindClient)
+	header := make(http.Header)
+	Inject(client.SpanContext(), header)
+
+	remote, ok := Extract(header)
+	if !ok {
+		t.Fatalf("extract failed from %v", header)
+	}
+	_, server := tracer.Start(ContextWithRemoteSpanContext(context.Background(), remote), "server", SpanKindServer)
+	server.End()
+	client.End()
+	client.End()
+
+	spans := exporter.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("expect 2 spans, got %d", len(spans))
+	}
+	if spans[0].SpanContext.TraceID != spans[1].SpanContext.TraceID {
+		t.Error("server span should share the trace id of client span")
+	}
+	if spans[0].ParentSpanID != client.SpanContext().SpanID {
+		t.Error("server span should be a child of client span")
+	}
+	if TraceIDFromContext(ctx) != client.SpanContext().TraceID.String() {
+		t.Error("trace id not found in context")
+	}
+}
+
+func TestLogrusHook(t *testing.T) {
+	var buf bytes.Buffer
+	log := logrus.New()
+	log.SetOutput(&buf)
+	log.AddHook(LogrusHook{})
+
+	ctx, span := NewTracer().Start(context.Background(), "op", SpanKindInternal)
+	log.WithContext(ctx).Info("hello")
+	if !strings.Contains(buf.String(), "trace_id="+span.SpanContext().TraceID.String()) {
+		t.Errorf("trace id not logged: %s", buf.String())
+	}
+}
@@ -0,0 +1,171 @@
+package balancer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mangohow/mangokit/transport/resolver"
+)
+
+// ErrNoEndpoint 没有可用的实例
+var ErrNoEndpoint = errors.New("balancer: no endpoint available")
+
+// Node 负载均衡中的实例节点
+type Node struct {
+	resolver.Endpoint
+
+	pending int64
+
+	// 以下字段由Balancer.mu保护
+	failures     int
+	ejectedUntil time.Time
+	// 平滑加权轮询的当前权重
+	current int
+}
+
+// Pending 正在进行的请求数量
+func (n *Node) Pending() int64 {
+	return atomic.LoadInt64(&n.pending)
+}
+
+// weight 节点权重, 未设置时为1
+func (n *Node) weight() int {
+	if n.Weight <= 0 {
+		return 1
+	}
+	return n.Weight
+}
+
+// Picker 负载均衡算法, 从可用节点中选择一个节点, nodes不为空
+// Pick在Balancer的锁内调用, 不需要额外加锁
+type Picker interface {
+	Pick(nodes []*Node) *Node
+}
+
+// DoneFunc 请求结束后调用, err不为nil时视为该节点请求失败
+type DoneFunc func(err error)
+
+type Option func(b *Balancer)
+
+// WithEjection 开启被动健康检查, 节点连续失败failures次后在duration时间内不会被选中
+// 所有节点都被剔除时仍然从全部节点中选择, 避免请求全部失败
+func WithEjection(failures int, duration time.Duration) Option {
+	return func(b *Balancer) {
+		b.maxFailures = failures
+		b.ejectDuration = duration
+	}
+}
+
+// Balancer 维护实例节点并使用Picker选择节点
+type Balancer struct {
+	mu     sync.Mutex
+	picker Picker
+	nodes  []*Node
+
+	maxFailures   int
+	ejectDuration time.Duration
+
+	now func() time.Time
+}
+
+func New(picker Picker, opts ...Option) *Balancer {
+	b := &Balancer{
+		picker: picker,
+		now:    time.Now,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b
+}
+
+// Update 更新实例列表, 已存在的节点保留其状态
+func (b *Balancer) Update(endpoints []resolver.Endpoint) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	old := make(map[string]*Node, len(b.nodes))
+	for _, n := range b.nodes {
+		old[n.Addr] = n
+	}
+	nodes := make([]*Node, 0, len(endpoints))
+	for _, ep := range endpoints {
+		if n, ok := old[ep.Addr]; ok {
+			n.Endpoint = ep
+			nodes = append(nodes, n)
+			continue
+		}
+		nodes = append(nodes, &Node{Endpoint: ep})
+	}
+	b.nodes = nodes
+}
+
+// Nodes 返回当前所有节点
+func (b *Balancer) Nodes() []*Node {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]*Node(nil), b.nodes...)
+}
+
+// Pick 选择一个节点, 请求结束后必须调用返回的DoneFunc
+func (b *Balancer) Pick(ctx context.Context) (*Node, DoneFunc, error) {
+	b.mu.Lock()
+	if len(b.nodes) == 0 {
+		b.mu.Unlock()
+		return nil, nil, ErrNoEndpoint
+	}
+	n := b.picker.Pick(b.available())
+	b.mu.Unlock()
+
+	atomic.AddInt64(&n.pending, 1)
+	var once sync.Once
+	return n, func(err error) {
+		once.Do(func() {
+			atomic.AddInt64(&n.pending, -1)
+			b.report(n, err)
+		})
+	}, nil
+}
+
+// available 返回未被剔除的节点, 全部被剔除时返回所有节点
+func (b *Balancer) available() []*Node {
+	if b.maxFailures <= 0 {
+		return b.nodes
+	}
+
+	now := b.now()
+	nodes := make([]*Node, 0, len(b.nodes))
+	for _, n := range b.nodes {
+		if now.After(n.ejectedUntil) {
+			nodes = append(nodes, n)
+		}
+	}
+	if len(nodes) == 0 {
+		return b.nodes
+	}
+
+	return nodes
+}
+
+func (b *Balancer) report(n *Node, err error) {
+	if b.maxFailures <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		n.failures = 0
+		return
+	}
+	n.failures++
+	if n.failures >= b.maxFailures {
+		n.failures = 0
+		n.ejectedUntil = b.now().Add(b.ejectDuration)
+	}
+}
@@ -0,0 +1,107 @@
+package balancer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mangohow/mangokit/transport/resolver"
+)
+
+func pickN(t *testing.T, b *Balancer, n int) string {
+	var addrs []string
+	for i := 0; i < n; i++ {
+		node, done, err := b.Pick(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		done(nil)
+		addrs = append(addrs, node.Addr)
+	}
+
+	return strings.Join(addrs, "")
+}
+
+func TestPickers(t *testing.T) {
+	endpoints := []resolver.Endpoint{{Addr: "a", Weight: 5}, {Addr: "b", Weight: 1}, {Addr: "c", Weight: 1}}
+
+	b := New(RoundRobin())
+	if _, _, err := b.Pick(context.Background()); err != ErrNoEndpoint {
+		t.Errorf("expect ErrNoEndpoint, got %v", err)
+	}
+	b.Update(endpoints)
+	if got := pickN(t, b, 6); got != "abcabc" {
+		t.Errorf("round robin: %s", got)
+	}
+
+	b = New(Weighted())
+	b.Update(endpoints)
+	if got := pickN(t, b, 7); got != "aabacaa" {
+		t.Errorf("weighted: %s", got)
+	}
+
+	b = New(Random())
+	b.Update(endpoints)
+	if got := pickN(t, b, 100); strings.Count(got, "a") == 100 {
+		t.Errorf("random: %s", got)
+	}
+
+	b = New(LeastPending())
+	b.Update(endpoints)
+	var dones []DoneFunc
+	seen := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		node, done, _ := b.Pick(context.Background())
+		seen[node.Addr] = true
+		dones = append(dones, done)
+	}
+	if len(seen) != 3 {
+		t.Errorf("least pending should pick idle nodes first, got %v", seen)
+	}
+	for _, done := range dones {
+		done(nil)
+		done(nil)
+	}
+	for _, n := range b.Nodes() {
+		if n.Pending() != 0 {
+			t.Errorf("node %s pending %d", n.Addr, n.Pending())
+		}
+	}
+}
+
+func TestEjection(t *testing.T) {
+	now := time.Now()
+	b := New(RoundRobin(), WithEjection(2, time.Minute))
+	b.now = func() time.Time { return now }
+	b.Update([]resolver.Endpoint{{Addr: "a"}, {Addr: "b"}})
+
+	fail := errors.New("connection refused")
+	for i := 0; i < 4; i++ {
+		node, done, _ := b.Pick(context.Background())
+		if node.Addr == "a" {
+			done(fail)
+		} else {
+			done(nil)
+		}
+	}
+	if got := pickN(t, b, 4); got != "bbbb" {
+		t.Errorf("a should be ejected, got %s", got)
+	}
+
+	// 所有节点都被剔除时从全部节点中选择
+	for i := 0; i < 2; i++ {
+		_, done, _ := b.Pick(context.Background())
+		done(fail)
+	}
+	if got := pickN(t, b, 2); got != "ab" && got != "ba" {
+		t.Errorf("expect fallback to all nodes, got %s", got)
+	}
+
+	now = now.Add(2 * time.Minute)
+	b.Update([]resolver.Endpoint{{Addr: "a"}, {Addr: "b"}})
+	if got := pickN(t, b, 4); strings.Count(got, "a") != 2 {
+		t.Errorf("a should be back after ejection, got %s", got)
+	}
+}
@@ -0,0 +1,77 @@
+package balancer
+
+import (
+	"math/rand"
+)
+
+type roundRobin struct {
+	next int
+}
+
+// RoundRobin 轮询
+func RoundRobin() Picker {
+	return &roundRobin{}
+}
+
+func (p *roundRobin) Pick(nodes []*Node) *Node {
+	n := nodes[p.next%len(nodes)]
+	p.next = (p.next + 1) % len(nodes)
+	return n
+}
+
+type random struct{}
+
+// Random 随机选择
+func Random() Picker {
+	return random{}
+}
+
+func (random) Pick(nodes []*Node) *Node {
+	return nodes[rand.Intn(len(nodes))]
+}
+
+type weighted struct{}
+
+// Weighted 平滑加权轮询, 节点被选中的比例与Endpoint.Weight成正比, 且选择结果分布均匀
+func Weighted() Picker {
+	return weighted{}
+}
+
+func (weighted) Pick(nodes []*Node) *Node {
+	var (
+		total int
+		best  *Node
+	)
+	for _, n := range nodes {
+		n.current += n.weight()
+		total += n.weight()
+		if best == nil || n.current > best.current {
+			best = n
+		}
+	}
+	best.current -= total
+
+	return best
+}
+
+type leastPending struct {
+	next int
+}
+
+// LeastPending 选择正在进行的请求数量最少的节点, 数量相同时轮询
+func LeastPending() Picker {
+	return &leastPending{}
+}
+
+func (p *leastPending) Pick(nodes []*Node) *Node {
+	p.next = (p.next + 1) % len(nodes)
+	var best *Node
+	for i := range nodes {
+		n := nodes[(p.next+i)%len(nodes)]
+		if best == nil || n.Pending() < best.Pending() {
+			best = n
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,325 @@
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOpen 熔断器处于打开状态, 请求被拒绝
+var ErrOpen = errors.New("breaker: circuit breaker is open")
+
+// State 熔断器状态
+type State int
+
+const (
+	// StateClosed 关闭状态, 请求正常通过并统计失败率
+	StateClosed State = iota
+	// StateOpen 打开状态, 所有请求直接失败, 经过OpenTimeout后进入半开状态
+	StateOpen
+	// StateHalfOpen 半开状态, 允许少量探测请求通过, 全部成功则关闭, 否则重新打开
+	StateHalfOpen
+)
+
+// Outcome 请求结果, 由Allow返回的done上报
+type Outcome int
+
+const (
+	// Success 请求成功
+	Success Outcome = iota
+	// Failure 请求失败, 计入失败率, 半开状态下会重新打开熔断器
+	Failure
+	// Ignored 没有结果, 例如调用方取消了请求, 不计入统计也不改变状态, 半开状态下释放探测名额
+	Ignored
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+type options struct {
+	window           time.Duration
+	buckets          int
+	failureRatio     float64
+	minRequests      int64
+	openTimeout      time.Duration
+	halfOpenRequests int
+	probeTimeout     time.Duration
+	onStateChange    func(name string, from, to State)
+	now              func() time.Time
+}
+
+type Option func(o *options)
+
+// WithWindow 设置统计失败率的滑动窗口大小以及窗口中桶的数量, 默认为10s, 10个桶
+func WithWindow(window time.Duration, buckets int) Option {
+	return func(o *options) {
+		o.window = window
+		o.buckets = buckets
+	}
+}
+
+// WithFailureRatio 设置打开熔断器的失败率, 默认为0.5
+func WithFailureRatio(ratio float64) Option {
+	return func(o *options) {
+		o.failureRatio = ratio
+	}
+}
+
+// WithMinRequests 设置窗口内计算失败率所需的最少请求数, 默认为20
+func WithMinRequests(n int64) Option {
+	return func(o *options) {
+		o.minRequests = n
+	}
+}
+
+// WithOpenTimeout 设置熔断器打开后进入半开状态的时间, 默认为5s
+func WithOpenTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.openTimeout = timeout
+	}
+}
+
+// WithHalfOpenRequests 设置半开状态允许通过的探测请求数量, 默认为1
+func WithHalfOpenRequests(n int) Option {
+	return func(o *options) {
+		o.halfOpenRequests = n
+	}
+}
+
+// WithProbeTimeout 设置半开状态下等待探测请求上报结果的时间, 默认为10s
+// 超时后视为探测失败, 熔断器重新打开, 避免done没有被调用时一直处于半开状态
+func WithProbeTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.probeTimeout = timeout
+	}
+}
+
+// WithStateChange 设置状态变化的回调, 可用于记录日志和指标, 回调在熔断器的锁外执行
+func WithStateChange(fn func(name string, from, to State)) Option {
+	return func(o *options) {
+		o.onStateChange = fn
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		window:           10 * time.Second,
+		buckets:          10,
+		failureRatio:     0.5,
+		minRequests:      20,
+		openTimeout:      5 * time.Second,
+		halfOpenRequests: 1,
+		probeTimeout:     10 * time.Second,
+		now:              time.Now,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.buckets <= 0 {
+		o.buckets = 1
+	}
+	if o.halfOpenRequests <= 0 {
+		o.halfOpenRequests = 1
+	}
+
+	return o
+}
+
+type bucket struct {
+	epoch    int64
+	total    int64
+	failures int64
+}
+
+// Breaker 熔断器, 在滑动窗口内统计请求的失败率, 超过阈值时打开
+type Breaker struct {
+	name string
+	o    *options
+
+	mu       sync.Mutex
+	state    State
+	buckets  []bucket
+	openedAt time.Time
+	// generation 每次状态变化时加1, 之前放行的请求上报的结果不再计入
+	generation uint64
+	// 半开状态下已放行和已成功的探测请求数量, 以及最后一个探测请求放行的时间
+	probes    int
+	successes int
+	probeAt   time.Time
+}
+
+func New(name string, opts ...Option) *Breaker {
+	return newBreaker(name, newOptions(opts))
+}
+
+func newBreaker(name string, o *options) *Breaker {
+	return &Breaker{
+		name:    name,
+		o:       o,
+		buckets: make([]bucket, o.buckets),
+	}
+}
+
+// Name 熔断器名称
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State 熔断器当前状态
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state, notify := b.currentState(b.o.now())
+	defer notify()
+
+	return state
+}
+
+// Allow 判断请求是否可以通过, 熔断器打开时返回ErrOpen
+// 请求通过时返回done, 请求结束后必须调用done上报请求的结果
+func (b *Breaker) Allow() (done func(outcome Outcome), err error) {
+	b.mu.Lock()
+	now := b.o.now()
+	state, notify := b.currentState(now)
+	switch state {
+	case StateOpen:
+		b.mu.Unlock()
+		notify()
+		return nil, ErrOpen
+	case StateHalfOpen:
+		if b.probes >= b.o.halfOpenRequests {
+			b.mu.Unlock()
+			notify()
+			return nil, ErrOpen
+		}
+		b.probes++
+		b.probeAt = now
+	}
+	generation := b.generation
+	b.mu.Unlock()
+	notify()
+
+	var once sync.Once
+	return func(outcome Outcome) {
+		once.Do(func() {
+			b.report(generation, outcome)
+		})
+	}, nil
+}
+
+// currentState 打开状态超过OpenTimeout后进入半开状态, 探测请求超过ProbeTimeout没有上报结果时重新打开
+// 返回的notify需要在锁外调用
+func (b *Breaker) currentState(now time.Time) (State, func()) {
+	switch {
+	case b.state == StateOpen && now.Sub(b.openedAt) >= b.o.openTimeout:
+		return StateHalfOpen, b.setState(StateHalfOpen, now)
+	case b.state == StateHalfOpen && b.probes > b.successes && now.Sub(b.probeAt) >= b.o.probeTimeout:
+		return StateOpen, b.setState(StateOpen, now)
+	}
+	return b.state, func() {}
+}
+
+func (b *Breaker) report(generation uint64, outcome Outcome) {
+	b.mu.Lock()
+	now := b.o.now()
+	notify := func() {}
+	if generation != b.generation {
+		// 状态已经变化, 例如探测请求超时后熔断器重新打开
+		b.mu.Unlock()
+		return
+	}
+	switch b.state {
+	case StateClosed:
+		if outcome == Ignored {
+			break
+		}
+		bk := b.bucket(now)
+		bk.total++
+		if outcome == Failure {
+			bk.failures++
+		}
+		if total, failures := b.sum(now); total >= b.o.minRequests &&
+			float64(failures)/float64(total) >= b.o.failureRatio {
+			notify = b.setState(StateOpen, now)
+		}
+	case StateHalfOpen:
+		if outcome == Ignored {
+			b.probes--
+			break
+		}
+		if outcome == Failure {
+			notify = b.setState(StateOpen, now)
+			break
+		}
+		b.successes++
+		if b.successes >= b.o.halfOpenRequests {
+			notify = b.setState(StateClosed, now)
+		}
+	}
+	b.mu.Unlock()
+	notify()
+}
+
+// setState 切换状态并重置统计数据, 返回状态变化的通知函数
+func (b *Breaker) setState(state State, now time.Time) func() {
+	from := b.state
+	b.state = state
+	b.generation++
+	b.probes, b.successes = 0, 0
+	switch state {
+	case StateOpen:
+		b.openedAt = now
+	case StateClosed:
+		for i := range b.buckets {
+			b.buckets[i] = bucket{}
+		}
+	}
+
+	if b.o.onStateChange == nil || from == state {
+		return func() {}
+	}
+	name, fn := b.name, b.o.onStateChange
+	return func() {
+		fn(name, from, state)
+	}
+}
+
+func (b *Breaker) bucketDuration() int64 {
+	d := int64(b.o.window) / int64(len(b.buckets))
+	if d <= 0 {
+		d = 1
+	}
+	return d
+}
+
+// bucket 返回当前时间对应的桶, 桶过期时先清空
+func (b *Breaker) bucket(now time.Time) *bucket {
+	epoch := now.UnixNano() / b.bucketDuration()
+	bk := &b.buckets[epoch%int64(len(b.buckets))]
+	if bk.epoch != epoch {
+		*bk = bucket{epoch: epoch}
+	}
+	return bk
+}
+
+// sum 统计窗口内的请求总数和失败数
+func (b *Breaker) sum(now time.Time) (total, failures int64) {
+	epoch := now.UnixNano() / b.bucketDuration()
+	for _, bk := range b.buckets {
+		if epoch-bk.epoch < int64(len(b.buckets)) {
+			total += bk.total
+			failures += bk.failures
+		}
+	}
+	return
+}
@@ -0,0 +1,118 @@
+package breaker
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+	var transitions []string
+	g := NewGroup(
+		WithWindow(time.Second, 10),
+		WithMinRequests(4),
+		WithFailureRatio(0.5),
+		WithOpenTimeout(time.Second),
+		WithHalfOpenRequests(2),
+		WithStateChange(func(name string, from, to State) {
+			transitions = append(transitions, name+": "+from.String()+" -> "+to.String())
+		}),
+	)
+	g.o.now = func() time.Time { return now }
+	b := g.Get("user")
+	if g.Get("user") != b {
+		t.Fatal("group should reuse breaker")
+	}
+
+	call := func(outcome Outcome) error {
+		done, err := b.Allow()
+		if err != nil {
+			return err
+		}
+		done(outcome)
+		return nil
+	}
+
+	// 窗口外的失败不计入
+	call(Failure)
+	call(Failure)
+	now = now.Add(2 * time.Second)
+	for _, outcome := range []Outcome{Success, Failure, Success, Ignored} {
+		call(outcome)
+	}
+	if b.State() != StateClosed {
+		t.Fatalf("expect closed, got %v", b.State())
+	}
+	call(Failure)
+	if b.State() != StateOpen || call(Success) != ErrOpen {
+		t.Fatalf("expect open, got %v", b.State())
+	}
+
+	now = now.Add(time.Second)
+	done1, err1 := b.Allow()
+	done2, err2 := b.Allow()
+	if err1 != nil || err2 != nil || call(Success) != ErrOpen {
+		t.Fatal("half open should allow exactly 2 probes")
+	}
+	done1(Success)
+	done2(Failure)
+	if b.State() != StateOpen {
+		t.Fatalf("failed probe should reopen, got %v", b.State())
+	}
+
+	// 取消的探测请求释放名额, 不改变状态
+	now = now.Add(time.Second)
+	call(Ignored)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("ignored probe should keep half open, got %v", b.State())
+	}
+	call(Success)
+	call(Success)
+	if b.State() != StateClosed {
+		t.Fatalf("expect closed after successful probes, got %v", b.State())
+	}
+
+	expect := []string{
+		"user: closed -> open",
+		"user: open -> half-open",
+		"user: half-open -> open",
+		"user: open -> half-open",
+		"user: half-open -> closed",
+	}
+	if !reflect.DeepEqual(transitions, expect) {
+		t.Errorf("unexpected transitions %v", transitions)
+	}
+}
+
+func TestBreakerProbeTimeout(t *testing.T) {
+	now := time.Now()
+	g := NewGroup(WithMinRequests(1), WithOpenTimeout(time.Second), WithProbeTimeout(time.Second))
+	g.o.now = func() time.Time { return now }
+	b := g.Get("user")
+
+	done, _ := b.Allow()
+	done(Failure)
+	now = now.Add(time.Second)
+	lost, err := b.Allow()
+	if err != nil || b.State() != StateHalfOpen {
+		t.Fatalf("expect half open probe, got %v %v", b.State(), err)
+	}
+
+	// 探测请求没有上报结果, 超时后重新打开
+	now = now.Add(time.Second)
+	if b.State() != StateOpen {
+		t.Fatalf("expect open after probe timeout, got %v", b.State())
+	}
+	now = now.Add(time.Second)
+	probe, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 超时的探测请求上报的结果不再计入
+	lost(Failure)
+	probe(Success)
+	if b.State() != StateClosed {
+		t.Fatalf("expect closed, got %v", b.State())
+	}
+}
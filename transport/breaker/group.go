@@ -0,0 +1,31 @@
+package breaker
+
+import "sync"
+
+// Group 按照key管理熔断器, 例如每个实例地址或每个接口一个熔断器, 所有熔断器使用相同的配置
+type Group struct {
+	o        *options
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+}
+
+func NewGroup(opts ...Option) *Group {
+	return &Group{
+		o:        newOptions(opts),
+		breakers: make(map[string]*Breaker),
+	}
+}
+
+// Get 获取key对应的熔断器, 不存在时创建, key作为熔断器的名称
+func (g *Group) Get(key string) *Breaker {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	b, ok := g.breakers[key]
+	if !ok {
+		b = newBreaker(key, g.o)
+		g.breakers[key] = b
+	}
+
+	return b
+}
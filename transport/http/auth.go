@@ -0,0 +1,310 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	stderr "errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mangohow/mangokit/errors"
+)
+
+const (
+	// UnauthorizedReason 认证失败时错误的reason, 响应码为401
+	UnauthorizedReason = "UNAUTHORIZED"
+	// TokenExpiredReason token过期时错误的reason, 响应码为401, 客户端可以据此刷新token
+	TokenExpiredReason = "TOKEN_EXPIRED"
+	// ForbiddenReason 认证成功但没有权限时错误的reason, 响应码为403
+	ForbiddenReason = "FORBIDDEN"
+
+	// DefaultAPIKeyHeader 默认传递API key的请求头
+	DefaultAPIKeyHeader = "X-Api-Key"
+)
+
+// 认证方式
+const (
+	SchemeJWT    = "jwt"
+	SchemeAPIKey = "apikey"
+	SchemeBasic  = "basic"
+)
+
+// Principal 认证通过的调用方
+type Principal struct {
+	// Subject 调用方标识, JWT中为sub, basic auth中为用户名, API key由校验函数设置
+	Subject string
+	// Scheme 认证方式, SchemeJWT, SchemeAPIKey或SchemeBasic
+	Scheme string
+	// Claims JWT中的claims, 其他认证方式可以由校验函数设置
+	Claims Claims
+}
+
+type principalKey struct{}
+
+// NewPrincipalContext 将Principal存入context
+func NewPrincipalContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext 从context中获取认证通过的调用方
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// ClaimsFromContext 从context中获取JWT的claims
+func ClaimsFromContext(ctx context.Context) (Claims, bool) {
+	p, ok := PrincipalFromContext(ctx)
+	if !ok || p.Claims == nil {
+		return nil, false
+	}
+	return p.Claims, true
+}
+
+type authOptions struct {
+	skip       []string
+	skipFunc   func(ctx context.Context) bool
+	authorizer func(ctx context.Context, p *Principal) bool
+	header     string
+	realm      string
+	issuer     string
+	audience   string
+	leeway     time.Duration
+}
+
+type AuthOption func(o *authOptions)
+
+// WithAuthSkip 跳过认证的接口, 可以是proto完整方法名, 路由模板或者 "方法 路由模板",
+// 例如 "/helloworld.v1.Greeter/SayHello", "/public/:id", "GET /healthz"
+func WithAuthSkip(routes ...string) AuthOption {
+	return func(o *authOptions) {
+		o.skip = append(o.skip, routes...)
+	}
+}
+
+// WithAuthSkipFunc 自定义跳过认证的条件
+func WithAuthSkipFunc(fn func(ctx context.Context) bool) AuthOption {
+	return func(o *authOptions) {
+		o.skipFunc = fn
+	}
+}
+
+// WithAuthorizer 认证通过后进行鉴权, 返回false时返回errors.Forbidden
+func WithAuthorizer(fn func(ctx context.Context, p *Principal) bool) AuthOption {
+	return func(o *authOptions) {
+		o.authorizer = fn
+	}
+}
+
+// WithAPIKeyHeader 设置传递API key的请求头, 默认为X-Api-Key
+func WithAPIKeyHeader(header string) AuthOption {
+	return func(o *authOptions) {
+		o.header = header
+	}
+}
+
+// WithRealm 设置basic auth的realm
+func WithRealm(realm string) AuthOption {
+	return func(o *authOptions) {
+		o.realm = realm
+	}
+}
+
+// WithIssuer 校验JWT的iss
+func WithIssuer(issuer string) AuthOption {
+	return func(o *authOptions) {
+		o.issuer = issuer
+	}
+}
+
+// WithAudience 校验JWT的aud包含audience
+func WithAudience(audience string) AuthOption {
+	return func(o *authOptions) {
+		o.audience = audience
+	}
+}
+
+// WithLeeway 校验JWT的exp和nbf时允许的时钟误差
+func WithLeeway(leeway time.Duration) AuthOption {
+	return func(o *authOptions) {
+		o.leeway = leeway
+	}
+}
+
+func newAuthOptions(opts []AuthOption) *authOptions {
+	o := &authOptions{
+		header: DefaultAPIKeyHeader,
+		realm:  "mangokit",
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func (o *authOptions) skipped(ctx context.Context, tr *Transport) bool {
+	if o.skipFunc != nil && o.skipFunc(ctx) {
+		return true
+	}
+	for _, route := range o.skip {
+		if route == tr.Operation() || route == tr.PathTemplate() || route == tr.Method()+" "+tr.PathTemplate() {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator 认证函数, 认证通过时返回携带Principal的ctx
+// 使用Server.Auth或Server.GroupAuth添加, 在解析请求参数之前执行, 未认证的请求返回401而不是参数错误
+type Authenticator func(ctx context.Context) (context.Context, error)
+
+// newAuthenticator 认证的公共流程: 跳过检查, 认证, 鉴权以及将Principal存入context
+// challenge为认证失败时WWW-Authenticate响应头的值
+// 认证失败的原因不会返回给客户端, 而是作为错误的cause由错误处理函数打印到日志中
+func newAuthenticator(o *authOptions, challenge string, auth func(ctx context.Context, tr *Transport) (*Principal, error)) Authenticator {
+	return func(ctx context.Context) (context.Context, error) {
+		tr, ok := FromServerContext(ctx)
+		if !ok {
+			return nil, errors.Unauthorized(http.StatusUnauthorized, UnauthorizedReason, "unauthorized")
+		}
+		if o.skipped(ctx, tr) {
+			return ctx, nil
+		}
+
+		p, err := auth(ctx, tr)
+		if err != nil {
+			tr.ReplyHeader().Set("WWW-Authenticate", challenge)
+			if e, ok := err.(errors.Error); ok {
+				return nil, e
+			}
+			if stderr.Is(err, errTokenExpired) {
+				return nil, errors.UnauthorizedCause(http.StatusUnauthorized, TokenExpiredReason, "token expired", err)
+			}
+			return nil, errors.UnauthorizedCause(http.StatusUnauthorized, UnauthorizedReason, "unauthorized", err)
+		}
+		if o.authorizer != nil && !o.authorizer(ctx, p) {
+			return nil, errors.Forbidden(http.StatusForbidden, ForbiddenReason, "permission denied")
+		}
+
+		return NewPrincipalContext(ctx, p), nil
+	}
+}
+
+// authenticate 依次执行认证函数
+func authenticate(ctx context.Context, auths []Authenticator) (context.Context, error) {
+	for _, auth := range auths {
+		var err error
+		if ctx, err = auth(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return ctx, nil
+}
+
+// JWT 校验Authorization: Bearer <token>, 支持HS256/384/512和RS256/384/512
+// 校验通过后可以使用ClaimsFromContext获取claims
+func JWT(keyFunc JWTKeyFunc, opts ...AuthOption) Authenticator {
+	o := newAuthOptions(opts)
+	return newAuthenticator(o, "Bearer", func(ctx context.Context, tr *Transport) (*Principal, error) {
+		token, ok := bearerToken(tr.RequestHeader().Get("Authorization"))
+		if !ok {
+			return nil, stderr.New("missing bearer token")
+		}
+		claims, err := ParseJWT(token, keyFunc)
+		if err != nil {
+			return nil, err
+		}
+		if err = validateClaims(claims, o); err != nil {
+			return nil, err
+		}
+
+		return &Principal{Subject: claims.Subject(), Scheme: SchemeJWT, Claims: claims}, nil
+	})
+}
+
+func bearerToken(auth string) (string, bool) {
+	const prefix = "Bearer "
+	if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(auth[len(prefix):]), true
+}
+
+// APIKeyValidator 校验API key, 返回调用方信息, 校验失败返回error, 返回的调用方信息为nil时同样认为校验失败
+type APIKeyValidator func(ctx context.Context, key string) (*Principal, error)
+
+// StaticAPIKeys 使用固定的API key, keys为 key -> subject
+func StaticAPIKeys(keys map[string]string) APIKeyValidator {
+	return func(ctx context.Context, key string) (*Principal, error) {
+		for k, subject := range keys {
+			if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+				return &Principal{Subject: subject}, nil
+			}
+		}
+		return nil, stderr.New("invalid api key")
+	}
+}
+
+// APIKey 校验请求头中的API key, 请求头默认为X-Api-Key
+func APIKey(validator APIKeyValidator, opts ...AuthOption) Authenticator {
+	o := newAuthOptions(opts)
+	return newAuthenticator(o, "APIKey", func(ctx context.Context, tr *Transport) (*Principal, error) {
+		key := tr.RequestHeader().Get(o.header)
+		if key == "" {
+			return nil, stderr.New("missing api key")
+		}
+		p, err := validator(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if p == nil {
+			return nil, stderr.New("invalid api key")
+		}
+		// validator可能返回共享的Principal, 复制后再设置认证方式
+		principal := *p
+		principal.Scheme = SchemeAPIKey
+		return &principal, nil
+	})
+}
+
+// BasicAuthValidator 校验用户名和密码
+type BasicAuthValidator func(ctx context.Context, username, password string) bool
+
+// StaticAccounts 使用固定的账号, accounts为 用户名 -> 密码
+func StaticAccounts(accounts map[string]string) BasicAuthValidator {
+	return func(ctx context.Context, username, password string) bool {
+		expect, ok := accounts[username]
+		return ok && subtle.ConstantTimeCompare([]byte(expect), []byte(password)) == 1
+	}
+}
+
+// BasicAuth 校验Authorization: Basic <credentials>, Principal.Subject为用户名
+func BasicAuth(validator BasicAuthValidator, opts ...AuthOption) Authenticator {
+	o := newAuthOptions(opts)
+	return newAuthenticator(o, `Basic realm="`+o.realm+`"`, func(ctx context.Context, tr *Transport) (*Principal, error) {
+		username, password, ok := parseBasicAuth(tr.RequestHeader().Get("Authorization"))
+		if !ok {
+			return nil, stderr.New("missing basic auth credentials")
+		}
+		if !validator(ctx, username, password) {
+			return nil, stderr.New("invalid username or password")
+		}
+		return &Principal{Subject: username, Scheme: SchemeBasic}, nil
+	})
+}
+
+func parseBasicAuth(auth string) (username, password string, ok bool) {
+	const prefix = "Basic "
+	if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return "", "", false
+	}
+	data, err := base64.StdEncoding.DecodeString(auth[len(prefix):])
+	if err != nil {
+		return "", "", false
+	}
+	username, password, ok = strings.Cut(string(data), ":")
+	return
+}
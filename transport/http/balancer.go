@@ -0,0 +1,99 @@
+package http
+
+import (
+	"context"
+	stderr "errors"
+	"fmt"
+	"net/http"
+
+	"github.com/mangohow/mangokit/errors"
+	"github.com/mangohow/mangokit/transport/balancer"
+	"github.com/mangohow/mangokit/transport/resolver"
+)
+
+// NoEndpointReason 没有可用的服务实例时错误的reason
+const NoEndpointReason = "NO_ENDPOINT"
+
+// initBalancer 解析服务实例并在后台监听实例变化
+func (c *Client) initBalancer() error {
+	r := c.config.resolver
+	if r == nil {
+		r = resolver.NewStatic(c.config.host)
+	}
+	c.balancer = c.config.balancer
+	if c.balancer == nil {
+		c.balancer = balancer.New(balancer.RoundRobin())
+	}
+
+	endpoints, err := r.Resolve(context.Background())
+	if err != nil {
+		return fmt.Errorf("resolve endpoints failed, %v", err)
+	}
+	c.balancer.Update(endpoints)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	go r.Watch(ctx, c.balancer.Update)
+
+	return nil
+}
+
+// pick 选择本次请求的实例地址并检查熔断器, 请求结束后需要调用返回的函数上报结果
+func (c *Client) pick(ctx context.Context, tr *ClientTransport) (string, func(status int, err error), error) {
+	var (
+		base    = c.config.host
+		reports []func(status int, err error)
+	)
+	report := func(status int, err error) {
+		for _, fn := range reports {
+			fn(status, err)
+		}
+	}
+
+	if c.config.breakers != nil && c.config.breakerScope == BreakerPerOperation {
+		done, err := c.allow(operationKey(tr))
+		if err != nil {
+			return "", nil, err
+		}
+		reports = append(reports, done)
+	}
+
+	if c.balancer != nil {
+		node, done, err := c.balancer.Pick(ctx)
+		if err != nil {
+			report(0, err)
+			return "", nil, errors.ServiceUnavailableCause(http.StatusServiceUnavailable, NoEndpointReason, err.Error(), err)
+		}
+		base = node.URL()
+		reports = append(reports, func(status int, err error) {
+			done(nodeFailure(status, err))
+		})
+	}
+
+	if c.config.breakers != nil && c.config.breakerScope == BreakerPerEndpoint {
+		done, err := c.allow(base)
+		if err != nil {
+			// 熔断器打开的实例视为失败, 使负载均衡器尽快剔除该实例
+			report(0, err)
+			return "", nil, err
+		}
+		reports = append(reports, done)
+	}
+
+	return base, report, nil
+}
+
+// nodeFailure 网络错误和5xx响应视为实例失败, 调用方取消或超时不计入
+func nodeFailure(status int, err error) error {
+	if err != nil {
+		if stderr.Is(err, context.Canceled) || stderr.Is(err, context.DeadlineExceeded) {
+			return nil
+		}
+		return err
+	}
+	if status >= http.StatusInternalServerError {
+		return fmt.Errorf("http status %d", status)
+	}
+
+	return nil
+}
@@ -0,0 +1,356 @@
+package http
+
+import (
+	"encoding"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/mangohow/mangokit/errors"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// BindReason 请求参数解析失败时错误的reason
+const BindReason = "BIND_ERROR"
+
+// bindError 参数解析失败, Metadata中的parameter为解析失败的参数名
+func bindError(kind, name string, err error) error {
+	e := errors.BadRequestCause(http.StatusBadRequest, BindReason, fmt.Sprintf("invalid %s parameter %q: %v", kind, name, err), err)
+
+	return errors.Clone(e).WithMetadata("parameter", name)
+}
+
+// bindQuery 使用form tag解析query参数, 编码规则与EncodeURL一致, 解析完成后执行gin的结构体校验
+// 与gin相同, 没有form tag的字段使用字段名作为参数名, time.Time字段支持time_format, time_utc以及time_location tag
+func bindQuery(query url.Values, val interface{}) error {
+	v := reflect.ValueOf(val)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("bind query: %T is not a pointer", val)
+	}
+	if _, err := bindValues(v.Elem(), query, FormKey, "query", "", newVisiting(v.Elem())); err != nil {
+		return err
+	}
+	if binding.Validator == nil {
+		return nil
+	}
+
+	return binding.Validator.ValidateStruct(val)
+}
+
+// bindParam 使用param tag解析路径参数, 支持 :a.b 形式的嵌套字段以及 *path 形式的catch-all参数
+func bindParam(ctx *gin.Context, val interface{}) error {
+	fullPath := ctx.FullPath()
+	if len(ctx.Params) == 0 || !strings.ContainsAny(fullPath, ":*") {
+		return nil
+	}
+	v := reflect.ValueOf(val)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("bind param: %T is not a pointer", val)
+	}
+
+	params := make(url.Values, len(ctx.Params))
+	for _, p := range ctx.Params {
+		params.Set(p.Key, p.Value)
+	}
+	// gin中catch-all参数的值以 / 开头, 去掉后与EncodeURL的参数值一致
+	if i := strings.LastIndex(fullPath, "/*"); i != -1 {
+		name := fullPath[i+2:]
+		params.Set(name, strings.TrimPrefix(params.Get(name), "/"))
+	}
+
+	_, err := bindValues(v.Elem(), params, ParamKey, "path", "", newVisiting(v.Elem()))
+	return err
+}
+
+// visitKey 正在解析的结构体类型以及参数前缀, 没有tag的嵌套字段前缀不变, 递归引用自身时会无限展开
+type visitKey struct {
+	t      reflect.Type
+	prefix string
+}
+
+func newVisiting(v reflect.Value) map[visitKey]bool {
+	return map[visitKey]bool{{t: v.Type()}: true}
+}
+
+// bindValues 将values中的值按照tagKey设置到结构体字段中, kind用于错误信息, 返回是否设置了参数值
+// visiting记录当前路径上正在解析的结构体, 用于跳过递归引用自身的message
+func bindValues(v reflect.Value, values url.Values, tagKey, kind, prefix string, visiting map[visitKey]bool) (bool, error) {
+	if v.Kind() != reflect.Struct {
+		return false, nil
+	}
+
+	var bound bool
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+		name := tagName(field, tagKey)
+
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if !isScalar(ft) {
+			nested := prefix
+			if name != "" {
+				nested = prefix + name + "."
+			}
+			ok, err := bindNested(fv, ft, values, tagKey, kind, nested, visiting)
+			if err != nil {
+				return false, err
+			}
+			bound = bound || ok
+			continue
+		}
+		if name == "" && tagKey == FormKey && field.Tag.Get(tagKey) != "-" {
+			// 与gin相同, 没有form tag的字段使用字段名
+			name = field.Name
+		}
+		if name == "" {
+			continue
+		}
+
+		key := prefix + name
+		vals, ok := values[key]
+		if ok {
+			bound = true
+		} else {
+			def, has := tagDefault(field, tagKey)
+			if !has {
+				continue
+			}
+			vals = []string{def}
+		}
+
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+			slice := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+			for j, s := range vals {
+				if err := setField(slice.Index(j), field, s); err != nil {
+					return false, bindError(kind, key, err)
+				}
+			}
+			fv.Set(slice)
+			continue
+		}
+		if err := setField(fv, field, vals[0]); err != nil {
+			return false, bindError(kind, key, err)
+		}
+	}
+
+	return bound, nil
+}
+
+// bindNested 解析嵌套结构体, 字段为nil指针时只有解析到参数才会创建, 避免为没有参数的message分配内存
+func bindNested(fv reflect.Value, ft reflect.Type, values url.Values, tagKey, kind, prefix string, visiting map[visitKey]bool) (bool, error) {
+	key := visitKey{t: ft, prefix: prefix}
+	if visiting[key] || (prefix != "" && !hasKeyPrefix(values, prefix)) {
+		return false, nil
+	}
+	visiting[key] = true
+	defer delete(visiting, key)
+
+	target, fresh := fv, false
+	for target.Kind() == reflect.Ptr {
+		if target.IsNil() {
+			target, fresh = reflect.New(ft).Elem(), true
+			break
+		}
+		target = target.Elem()
+	}
+
+	bound, err := bindValues(target, values, tagKey, kind, prefix, visiting)
+	if err != nil || !bound {
+		return false, err
+	}
+	if fresh {
+		allocValue(fv).Set(target)
+	}
+
+	return true, nil
+}
+
+func hasKeyPrefix(values url.Values, prefix string) bool {
+	for k := range values {
+		if strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// tagDefault 获取gin风格的默认值, 例如 form:"page,default=1"
+func tagDefault(field reflect.StructField, tagKey string) (string, bool) {
+	opts := strings.Split(field.Tag.Get(tagKey), ",")
+	for _, opt := range opts[1:] {
+		if strings.HasPrefix(opt, "default=") {
+			return strings.TrimPrefix(opt, "default="), true
+		}
+	}
+	return "", false
+}
+
+// allocValue 为nil指针分配内存, 返回指针指向的值
+func allocValue(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// setField 解析字段值, time.Time字段使用gin风格的time_format tag, 例如 time_format:"2006-01-02" 以及 time_format:"unix"
+func setField(v reflect.Value, field reflect.StructField, s string) error {
+	format := field.Tag.Get("time_format")
+	if format == "" || indirectType(v.Type()) != timeType {
+		return setValue(v, s)
+	}
+
+	t, err := parseTime(field, format, s)
+	if err != nil {
+		return err
+	}
+	allocValue(v).Set(reflect.ValueOf(t))
+	return nil
+}
+
+// parseTime 与gin的time_format规则一致, 支持unix, unixmilli, unixmicro, unixnano以及time_utc, time_location
+func parseTime(field reflect.StructField, format, s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	switch tf := strings.ToLower(format); tf {
+	case "unix", "unixmilli", "unixmicro", "unixnano":
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		switch tf {
+		case "unixmilli":
+			return time.UnixMilli(n), nil
+		case "unixmicro":
+			return time.UnixMicro(n), nil
+		case "unixnano":
+			return time.Unix(0, n), nil
+		}
+		return time.Unix(n, 0), nil
+	}
+
+	loc := time.Local
+	if utc, _ := strconv.ParseBool(field.Tag.Get("time_utc")); utc {
+		loc = time.UTC
+	}
+	if name := field.Tag.Get("time_location"); name != "" {
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			return time.Time{}, err
+		}
+		loc = l
+	}
+
+	return time.ParseInLocation(format, s, loc)
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// setValue 将字符串解析为字段的类型并赋值
+// 支持整数(检查溢出), 浮点数, bool, 字符串, proto枚举(名称或数字), google.protobuf包装类型, time.Time(RFC3339)以及encoding.TextUnmarshaler
+func setValue(v reflect.Value, s string) error {
+	for v.Kind() == reflect.Ptr {
+		if isWrapper(v.Type()) {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			return setValue(v.Elem().FieldByName("Value"), s)
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	if v.Type() == timeType {
+		// 与gin相同, 空字符串解析为零值时间
+		if s == "" {
+			v.Set(reflect.ValueOf(time.Time{}))
+			return nil
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok && !v.Type().Implements(enumType) {
+			return u.UnmarshalText([]byte(s))
+		}
+	}
+	if e, ok := v.Interface().(protoreflect.Enum); ok {
+		if ev := e.Descriptor().Values().ByName(protoreflect.Name(s)); ev != nil {
+			v.SetInt(int64(ev.Number()))
+			return nil
+		}
+		n, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return fmt.Errorf("unknown enum value %q of %s", s, e.Descriptor().FullName())
+		}
+		v.SetInt(n)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		v.SetBytes([]byte(s))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
@@ -1,16 +1,26 @@
 package http
 
-import "net/http"
+import (
+	"encoding/base64"
+	"net/http"
+	"time"
+)
 
 type BeforeCallInfo struct {
-	ContentType string
-	Header      http.Header
-	Value       interface{}
+	ContentType  string
+	Header       http.Header
+	Value        interface{}
+	Operation    string        // proto完整方法名, 由生成代码设置
+	PathTemplate string        // 路由模板, 由生成代码设置
+	Timeout      time.Duration // 单次请求的超时时间, 由TimeoutCallOption设置
+	Retry        *RetryPolicy  // 单次请求的重试策略, 由RetryCallOption设置
 }
 
 type AfterCallInfo struct {
-	Resp   interface{}
-	Status int
+	Resp    interface{}
+	Status  int
+	Retries int   // 重试次数, 不包括第一次请求
+	Err     error // 请求失败时的错误
 }
 
 // CallOption 在请求被调用前和调用后执行的handler
@@ -45,10 +55,103 @@ type headerCallOption struct {
 }
 
 func (c headerCallOption) Before(info *BeforeCallInfo) {
-	info.Header = c.headers
+	for k, v := range c.headers {
+		info.Header[http.CanonicalHeaderKey(k)] = v
+	}
 }
 
-// HeadersCallOption 为请求设置headers
+// HeadersCallOption 为请求设置headers, 与其他CallOption设置的header合并
 func HeadersCallOption(headers http.Header) CallOption {
 	return headerCallOption{headers: headers}
 }
+
+type operationCallOption struct {
+	EmptyCallOptions
+	operation string
+}
+
+func (c operationCallOption) Before(info *BeforeCallInfo) {
+	info.Operation = c.operation
+}
+
+// OperationCallOption 设置请求对应的proto完整方法名, 例如 /helloworld.v1.Greeter/SayHello
+func OperationCallOption(operation string) CallOption {
+	return operationCallOption{operation: operation}
+}
+
+type pathTemplateCallOption struct {
+	EmptyCallOptions
+	pattern string
+}
+
+func (c pathTemplateCallOption) Before(info *BeforeCallInfo) {
+	info.PathTemplate = c.pattern
+}
+
+// PathTemplateCallOption 设置请求的路由模板, 例如 /user/:id
+func PathTemplateCallOption(pattern string) CallOption {
+	return pathTemplateCallOption{pattern: pattern}
+}
+
+type timeoutCallOption struct {
+	EmptyCallOptions
+	timeout time.Duration
+}
+
+func (c timeoutCallOption) Before(info *BeforeCallInfo) {
+	info.Timeout = c.timeout
+}
+
+// TimeoutCallOption 设置单次请求的超时时间, 覆盖Client的默认超时时间
+func TimeoutCallOption(timeout time.Duration) CallOption {
+	return timeoutCallOption{timeout: timeout}
+}
+
+type bearerTokenCallOption struct {
+	EmptyCallOptions
+	token string
+}
+
+func (c bearerTokenCallOption) Before(info *BeforeCallInfo) {
+	info.Header.Set("Authorization", "Bearer "+c.token)
+}
+
+// BearerTokenCallOption 使用 Authorization: Bearer <token> 传递JWT
+func BearerTokenCallOption(token string) CallOption {
+	return bearerTokenCallOption{token: token}
+}
+
+type apiKeyCallOption struct {
+	EmptyCallOptions
+	header string
+	key    string
+}
+
+func (c apiKeyCallOption) Before(info *BeforeCallInfo) {
+	info.Header.Set(c.header, c.key)
+}
+
+// APIKeyCallOption 使用X-Api-Key请求头传递API key
+func APIKeyCallOption(key string) CallOption {
+	return apiKeyCallOption{header: DefaultAPIKeyHeader, key: key}
+}
+
+// APIKeyHeaderCallOption 使用指定的请求头传递API key
+func APIKeyHeaderCallOption(header, key string) CallOption {
+	return apiKeyCallOption{header: header, key: key}
+}
+
+type basicAuthCallOption struct {
+	EmptyCallOptions
+	username string
+	password string
+}
+
+func (c basicAuthCallOption) Before(info *BeforeCallInfo) {
+	info.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(c.username+":"+c.password)))
+}
+
+// BasicAuthCallOption 使用basic auth传递用户名和密码
+func BasicAuthCallOption(username, password string) CallOption {
+	return basicAuthCallOption{username: username, password: password}
+}
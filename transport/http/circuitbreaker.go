@@ -0,0 +1,58 @@
+package http
+
+import (
+	"context"
+	stderr "errors"
+	"net/http"
+
+	"github.com/mangohow/mangokit/errors"
+	"github.com/mangohow/mangokit/transport/breaker"
+)
+
+// BreakerOpenReason 熔断器打开时错误的reason
+const BreakerOpenReason = "CIRCUIT_BREAKER_OPEN"
+
+// BreakerScope 熔断器的粒度
+type BreakerScope int
+
+const (
+	// BreakerPerOperation 每个接口一个熔断器, 使用OperationCallOption设置的方法名, 未设置时使用请求方法和路由模板
+	BreakerPerOperation BreakerScope = iota
+	// BreakerPerEndpoint 每个实例地址一个熔断器, 配合WithResolver使用
+	BreakerPerEndpoint
+)
+
+func operationKey(tr *ClientTransport) string {
+	if tr.Operation() != "" {
+		return tr.Operation()
+	}
+	if tr.PathTemplate() != "" {
+		return tr.Method() + " " + tr.PathTemplate()
+	}
+	return tr.Method() + " " + tr.Path()
+}
+
+// allow 检查key对应的熔断器, 打开时返回errors.ServiceUnavailable
+func (c *Client) allow(key string) (func(status int, err error), error) {
+	done, err := c.config.breakers.Get(key).Allow()
+	if err != nil {
+		return nil, errors.ServiceUnavailableCause(http.StatusServiceUnavailable, BreakerOpenReason,
+			"circuit breaker is open: "+key, err)
+	}
+
+	return func(status int, err error) {
+		done(breakerOutcome(status, err))
+	}, nil
+}
+
+// breakerOutcome 调用方取消或超时没有结果, 不改变熔断器的状态, 网络错误和5xx响应视为失败
+func breakerOutcome(status int, err error) breaker.Outcome {
+	if stderr.Is(err, context.Canceled) || stderr.Is(err, context.DeadlineExceeded) {
+		return breaker.Ignored
+	}
+	if nodeFailure(status, err) != nil {
+		return breaker.Failure
+	}
+
+	return breaker.Success
+}
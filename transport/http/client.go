@@ -3,29 +3,49 @@ package http
 import (
 	"bytes"
 	"context"
-	"encoding/json"
-	"errors"
+	stdjson "encoding/json"
+	stderr "errors"
 	"io"
 	"net/http"
-	"reflect"
-	"strconv"
-	"strings"
+	"time"
+
+	"github.com/mangohow/mangokit/encoding"
+	"github.com/mangohow/mangokit/encoding/json"
+	"github.com/mangohow/mangokit/metrics"
+	"github.com/mangohow/mangokit/tracing"
+	"github.com/mangohow/mangokit/transport/balancer"
+	"github.com/mangohow/mangokit/transport/breaker"
+	"github.com/mangohow/mangokit/transport/resolver"
 )
 
 // Client http client
 type Client struct {
-	client *http.Client
-	config config
+	client   *http.Client
+	config   config
+	balancer *balancer.Balancer
+	cancel   context.CancelFunc
 }
 
 type config struct {
 	host         string
 	transport    http.RoundTripper
 	interceptors []Interceptor
+	envelope     bool
+	contentType  string
+	metrics      *clientMetrics
+	tracer       *tracing.Tracer
+	timeout      time.Duration
+	retry        *RetryPolicy
+	resolver     resolver.Resolver
+	balancer     *balancer.Balancer
+	breakers     *breaker.Group
+	breakerScope BreakerScope
 }
 
-// Interceptor 拦截器
-type Interceptor func() error
+// Interceptor 客户端拦截器, 与服务端Middleware签名相同, 按照添加顺序执行
+// 通过FromClientContext获取请求方法, 路径, 请求头等信息, 调用handler之后可以获取响应码和响应头
+// 不调用handler直接返回可以中断请求, 此时需要自行填充ClientTransport.Reply()
+type Interceptor = Middleware
 
 type ClientOption func(*config)
 
@@ -39,13 +59,27 @@ func NewClient(options ...ClientOption) (*Client, error) {
 
 	c.client.Transport = c.config.transport
 
-	if c.config.host == "" {
-		return nil, errors.New("host is required, use WithHost option to set host")
+	if c.config.host == "" && c.config.resolver == nil {
+		return nil, stderr.New("host is required, use WithEndpoint or WithResolver option to set host")
+	}
+
+	if c.config.resolver != nil || c.config.balancer != nil {
+		if err := c.initBalancer(); err != nil {
+			return nil, err
+		}
 	}
 
 	return c, nil
 }
 
+// Close 停止监听服务实例的变化, 未使用WithResolver时不需要调用
+func (c *Client) Close() error {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	return nil
+}
+
 func WithEndpoint(endpoint string) ClientOption {
 	return func(c *config) {
 		c.host = endpoint
@@ -58,16 +92,80 @@ func WithTransport(transport http.RoundTripper) ClientOption {
 	}
 }
 
+// WithInterceptors 添加客户端拦截器, 可用于注入认证信息, 记录日志, 请求签名等
 func WithInterceptors(interceptors ...Interceptor) ClientOption {
 	return func(c *config) {
 		c.interceptors = append(c.interceptors, interceptors...)
 	}
 }
 
-// Invoke 先执行全局拦截器，再执行CallOption中的before，最后再发起请求
-func (c *Client) Invoke(ctx context.Context, method, path string, req, resp interface{}, opts ...CallOption) (status int, err error) {
-	url := c.config.host + path
+// WithContentType 设置请求默认使用的Content-Type, 同时作为Accept, 默认为application/json
+// 可以使用ContentTypeCallOption为单次请求设置
+func WithContentType(contentType string) ClientOption {
+	return func(c *config) {
+		c.contentType = contentType
+	}
+}
+
+// WithClientMetrics 开启客户端指标, 记录请求数量, 耗时以及正在进行的请求数量
+func WithClientMetrics(reg *metrics.Registry) ClientOption {
+	return func(c *config) {
+		c.metrics = newClientMetrics(reg)
+	}
+}
 
+// WithClientTracer 开启链路追踪, 为每次请求创建span并在请求头中传递W3C traceparent
+func WithClientTracer(tracer *tracing.Tracer) ClientOption {
+	return func(c *config) {
+		c.tracer = tracer
+	}
+}
+
+// WithTimeout 设置请求的默认超时时间, 可以使用TimeoutCallOption为单次请求设置
+// 超时时间与ctx中的deadline取较早者, 并通过请求头传递给服务端
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *config) {
+		c.timeout = timeout
+	}
+}
+
+// WithResolver 使用服务发现获取服务实例, 每次请求(包括重试)通过负载均衡选择一个实例
+// 设置后WithEndpoint无效, 默认使用轮询, 可以通过WithBalancer修改
+func WithResolver(r resolver.Resolver) ClientOption {
+	return func(c *config) {
+		c.resolver = r
+	}
+}
+
+// WithBalancer 设置负载均衡器, 例如 balancer.New(balancer.Weighted(), balancer.WithEjection(5, 30*time.Second))
+// 网络错误和5xx响应会作为实例的失败上报给负载均衡器
+func WithBalancer(b *balancer.Balancer) ClientOption {
+	return func(c *config) {
+		c.balancer = b
+	}
+}
+
+// WithCircuitBreaker 开启熔断, scope决定按照接口还是按照实例地址使用不同的熔断器
+// 熔断器打开时请求直接返回reason为BreakerOpenReason的errors.ServiceUnavailable
+// 网络错误和5xx响应视为失败, 调用方取消或超时不计入
+func WithCircuitBreaker(group *breaker.Group, scope BreakerScope) ClientOption {
+	return func(c *config) {
+		c.breakers = group
+		c.breakerScope = scope
+	}
+}
+
+// WithDecodeEnvelope 服务端使用WithResponseEnvelope时, 从 {"data": resp} 中解析json响应, 其他格式的响应直接解析
+func WithDecodeEnvelope() ClientOption {
+	return func(c *config) {
+		c.envelope = true
+	}
+}
+
+// Invoke 先执行CallOption中的before, 再依次执行拦截器, 最后发起请求, 请求结束后执行CallOption中的after
+// 请求会在ctx取消或超时后中断, 此时返回的error满足errors.Is(err, ctx.Err())
+// 服务端返回错误响应码时, 返回从响应中解析出的errors.Error, 同时返回响应码
+func (c *Client) Invoke(ctx context.Context, method, path string, req, resp interface{}, opts ...CallOption) (status int, err error) {
 	bco := &BeforeCallInfo{
 		Header: make(http.Header),
 		Value:  req,
@@ -76,49 +174,129 @@ func (c *Client) Invoke(ctx context.Context, method, path string, req, resp inte
 		opt.Before(bco)
 	}
 
-	var bodyReader io.Reader
-	if req != nil {
-		bodyBytes, err := json.Marshal(req)
-		if err != nil {
-			return status, err
-		}
-		bodyReader = bytes.NewReader(bodyBytes)
+	timeout := bco.Timeout
+	if timeout <= 0 {
+		timeout = c.config.timeout
 	}
-	request, err := http.NewRequest(method, url, bodyReader)
-	if err != nil {
-		return
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
 
-	if bco.ContentType == "" && method != http.MethodGet {
-		request.Header.Set("Content-Type", "application/json")
-	} else {
-		request.Header.Set("Content-Type", bco.ContentType)
+	contentType := bco.ContentType
+	if contentType == "" {
+		contentType = c.config.contentType
+	}
+	codec := encoding.GetCodec(contentType)
+	if codec == nil {
+		codec = defaultCodec()
+	}
+
+	tr := &ClientTransport{
+		operation:    bco.Operation,
+		method:       method,
+		path:         path,
+		pathTemplate: bco.PathTemplate,
+		reqHeader:    make(http.Header, len(bco.Header)+2),
+		replyHeader:  make(http.Header),
+		reply:        resp,
+	}
+	if bco.ContentType != "" || method != http.MethodGet {
+		tr.reqHeader.Set("Content-Type", codec.ContentType())
 	}
 	for k, v := range bco.Header {
-		request.Header.Set(k, v[0])
+		tr.reqHeader[http.CanonicalHeaderKey(k)] = append([]string(nil), v...)
+	}
+	if tr.reqHeader.Get("Accept") == "" {
+		tr.reqHeader.Set("Accept", codec.ContentType())
 	}
+	ctx = NewClientContext(ctx, tr)
 
-	response, err := c.client.Do(request)
-	if err != nil {
-		return
+	if c.config.metrics != nil {
+		done := c.config.metrics.observe(bco.Operation, method, bco.PathTemplate)
+		defer func() {
+			done(status, err)
+		}()
 	}
-	defer response.Body.Close()
-	status = response.StatusCode
 
-	respBytes, err := io.ReadAll(response.Body)
-	if err != nil {
-		return
+	var span *tracing.Span
+	if c.config.tracer != nil {
+		ctx, span = startClientSpan(ctx, c.config.tracer, bco, method)
+		defer func() {
+			endSpan(span, status, err)
+		}()
+	}
+
+	policy := bco.Retry
+	if policy == nil {
+		policy = c.config.retry
 	}
 
-	if resp != nil && len(respBytes) > 0 && status >= 200 && status < 400 {
-		if err = json.Unmarshal(respBytes, resp); err != nil {
-			return
+	var retries int
+	invoker := func(ctx context.Context, req interface{}) (interface{}, error) {
+		var body []byte
+		if req != nil {
+			var err error
+			if body, err = codec.Marshal(req); err != nil {
+				return nil, err
+			}
+		}
+
+		var (
+			respBytes []byte
+			err       error
+		)
+		for attempt := 1; ; attempt++ {
+			retries = attempt - 1
+			var (
+				base string
+				done func(status int, err error)
+			)
+			if base, done, err = c.pick(ctx, tr); err != nil {
+				// 没有可用实例或熔断器打开, 由重试策略决定是否重试
+				tr.statusCode, tr.replyHeader, respBytes = 0, make(http.Header), nil
+			} else {
+				tr.statusCode, tr.replyHeader, respBytes, err = c.do(ctx, base+path, body, tr, span)
+				done(tr.statusCode, err)
+				if err == nil && !successStatus(tr.statusCode) {
+					err = decodeError(tr.replyHeader, replyCodec(tr.replyHeader, codec), tr.statusCode, respBytes)
+				}
+			}
+			if !policy.shouldRetry(ctx, attempt, method, tr.statusCode, err) || !policy.wait(ctx, attempt) {
+				break
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if resp != nil && len(respBytes) > 0 {
+			if err = c.decodeResponse(replyCodec(tr.replyHeader, codec), respBytes, resp); err != nil {
+				return nil, err
+			}
 		}
+
+		return resp, nil
 	}
 
+	if chain := chainHandler(c.config.interceptors); chain != nil {
+		_, err = chain(ctx, req, invoker)
+	} else {
+		_, err = invoker(ctx, req)
+	}
+	// 拦截器中断请求并且没有返回错误时, 视为请求成功
+	if err == nil && tr.statusCode == 0 {
+		tr.statusCode = http.StatusOK
+	}
+	status = tr.statusCode
+
+	// 请求失败时同样执行After, 没有收到响应时Status为0
 	aco := &AfterCallInfo{
-		Resp:   resp,
-		Status: status,
+		Resp:    resp,
+		Status:  status,
+		Retries: retries,
+		Err:     err,
 	}
 
 	for _, opt := range opts {
@@ -128,153 +306,70 @@ func (c *Client) Invoke(ctx context.Context, method, path string, req, resp inte
 	return
 }
 
-func EncodeURL(pattern string, obj interface{}, query bool) string {
-	strings.TrimSuffix(pattern, "/")
-	if pattern == "" || obj == nil {
-		return ""
-	}
-
-	var (
-		builder = &strings.Builder{}
-		encPam  = false
-		m       = make(map[string]string, 8)
-	)
-	i := strings.IndexByte(pattern, ':')
-	if i != -1 {
-		// /xxx/:yyy/:zzz
-		// /xxx
-		builder.WriteString(pattern[:i-1])
-		encPam = true
-	} else {
-		// /xxx/yyy
-		// /xxx/yyy
-		builder.WriteString(pattern)
+// do 发起一次请求, 每次重试都会使用body重新创建请求
+func (c *Client) do(ctx context.Context, url string, body []byte, tr *ClientTransport, span *tracing.Span) (int, http.Header, []byte, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
 	}
-	// 从obj中获取param来拼接路径
-	if encPam {
-		// /:yyy/:zzz
-		encodeParam(pattern[i-1:], obj, builder, m)
+	request, err := http.NewRequestWithContext(ctx, tr.method, url, bodyReader)
+	if err != nil {
+		return 0, make(http.Header), nil, err
 	}
 
-	// 从obj中获取form参数来拼接路径
-	// 如果对应的field字段为空，则不拼接
-	if query {
-		encodeQuery(obj, builder, m)
+	for k, v := range tr.reqHeader {
+		request.Header[k] = v
+	}
+	setTimeoutHeader(ctx, request.Header)
+	if span != nil {
+		// 使用负载均衡选择的实例地址, 重试时记录最后一次请求的地址
+		span.SetAttribute("http.url", url)
+		tracing.Inject(span.SpanContext(), request.Header)
 	}
 
-	return builder.String()
-}
+	response, err := c.client.Do(request)
+	if err != nil {
+		return 0, make(http.Header), nil, err
+	}
+	defer response.Body.Close()
 
-func EncodeURLFromForm(pattern string, obj interface{}) string {
-	if pattern == "" || obj == nil {
-		return ""
+	respBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		return response.StatusCode, response.Header, nil, err
 	}
-	builder := &strings.Builder{}
-	builder.WriteString(pattern)
-	encodeQuery(obj, builder, nil)
 
-	return builder.String()
+	return response.StatusCode, response.Header, respBytes, nil
 }
 
-func encodeQuery(obj interface{}, builder *strings.Builder, expect map[string]string) {
-	m := make(map[string]string)
-	// 利用反射从结构体中获取form请求参数
-	reflectGetValues(m, obj, FormKey)
-	// 如果一个字段既添加了param tag 又添加了form tag，则需要忽略form tag，因为已经在param参数中进行了设置
-	if expect != nil {
-		for k := range m {
-			if _, ok := expect[k]; ok {
-				delete(m, k)
-			}
-		}
-	}
-	if len(m) == 0 {
-		return
-	}
-	builder.WriteByte('?')
-	for k, v := range m {
-		if v != "" {
-			builder.WriteString(k + "=" + v)
-		}
+// replyCodec 根据响应的Content-Type选择解析响应使用的codec
+func replyCodec(header http.Header, codec encoding.Codec) encoding.Codec {
+	if rc := encoding.GetCodec(header.Get("Content-Type")); rc != nil {
+		return rc
 	}
+	return codec
 }
 
-func encodeParam(pattern string, obj interface{}, builder *strings.Builder, m map[string]string) {
-	paramPatterns := strings.Split(pattern, "/:")
-	paramPatterns = removeEmptyStringsInPlace(paramPatterns)
-	if len(paramPatterns) == 0 {
-		return
-	}
-
-	for _, p := range paramPatterns {
-		m[p] = ""
-	}
-
-	// 利用反射解析出param请求参数
-	reflectGetValues(m, obj, ParamKey)
-	for _, pp := range paramPatterns {
-		if v := m[pp]; v != "" {
-			builder.WriteByte('/')
-			builder.WriteString(v)
-		}
-	}
+// successStatus 只有2xx视为成功, 3xx等其他响应码按照错误响应解析
+func successStatus(status int) bool {
+	return status >= 200 && status < 300
 }
 
-func removeEmptyStringsInPlace(slice []string) []string {
-	n := 0
-	for _, str := range slice {
-		if str != "" {
-			slice[n] = str
-			n++
-		}
+func (c *Client) decodeResponse(codec encoding.Codec, data []byte, resp interface{}) error {
+	// 服务端只对json响应进行包装
+	if !c.config.envelope || codec.ContentType() != json.ContentType {
+		return codec.Unmarshal(data, resp)
 	}
-	return slice[:n]
-}
 
-func reflectGetValues(m map[string]string, obj interface{}, tagK string) {
-	rv := reflect.ValueOf(obj)
-	if rv.Kind() == reflect.Ptr && rv.IsNil() {
-		return
+	// data使用codec解析, proto.Message使用protojson
+	var envelope struct {
+		Data stdjson.RawMessage `json:"data"`
 	}
-	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
-		rv = rv.Elem()
+	if err := stdjson.Unmarshal(data, &envelope); err != nil {
+		return err
 	}
-
-	rt := rv.Type()
-	n := rt.NumField()
-	for i := 0; i < n; i++ {
-		// 获取tag，如果没有则该tag不添加到路径中
-		tagV := rt.Field(i).Tag.Get(tagK)
-		if tagV == "" {
-			continue
-		}
-
-		// 对于param参数，如果该tag没有在路径中声明，则不添加到路径中
-		// 对于form参数，全部添加到路径中
-		if _, ok := m[tagV]; !ok && tagK == ParamKey {
-			continue
-		}
-
-		fiv := rv.Field(i)
-		for fiv.Kind() == reflect.Ptr && !fiv.IsNil() {
-			fiv = fiv.Elem()
-		}
-		if fiv.Kind() == reflect.Ptr && fiv.IsNil() {
-			continue
-		}
-
-		switch fiv.Kind() {
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			m[tagV] = strconv.FormatInt(fiv.Int(), 10)
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			m[tagV] = strconv.FormatUint(fiv.Uint(), 10)
-		case reflect.Float32, reflect.Float64:
-			m[tagV] = strconv.FormatFloat(fiv.Float(), 'g', -1, 64)
-		case reflect.Bool:
-			m[tagV] = strconv.FormatBool(fiv.Bool())
-		case reflect.String:
-			m[tagV] = fiv.String()
-		default:
-		}
+	if len(envelope.Data) == 0 || string(envelope.Data) == "null" {
+		return nil
 	}
+
+	return codec.Unmarshal(envelope.Data, resp)
 }
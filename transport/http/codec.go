@@ -0,0 +1,74 @@
+package http
+
+import (
+	"io"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/mangohow/mangokit/encoding"
+	"github.com/mangohow/mangokit/encoding/json"
+	_ "github.com/mangohow/mangokit/encoding/msgpack"
+	_ "github.com/mangohow/mangokit/encoding/proto"
+)
+
+// defaultCodec 未指定Content-Type或者Content-Type未注册时使用json
+func defaultCodec() encoding.Codec {
+	return encoding.GetCodec(json.ContentType)
+}
+
+// requestCodec 根据请求的Content-Type获取codec, 表单等未注册的类型返回false, 由gin进行绑定
+func requestCodec(c *gin.Context) (encoding.Codec, bool) {
+	if c.Request.Method == "GET" || c.Request.Body == nil || c.Request.ContentLength == 0 {
+		return nil, false
+	}
+	codec := encoding.GetCodec(c.ContentType())
+
+	return codec, codec != nil
+}
+
+// responseCodec 优先根据Accept选择codec, 其次使用请求的Content-Type, 默认使用json
+func responseCodec(c *gin.Context) encoding.Codec {
+	if codec := encoding.NegotiateCodec(c.GetHeader("Accept")); codec != nil {
+		return codec
+	}
+	if codec := encoding.GetCodec(c.ContentType()); codec != nil {
+		return codec
+	}
+
+	return defaultCodec()
+}
+
+// decodeBody 使用codec解析请求体, 解析完成后执行gin的结构体校验, 与ShouldBind保持一致
+func decodeBody(c *gin.Context, codec encoding.Codec, val interface{}) error {
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+	if err = codec.Unmarshal(data, val); err != nil {
+		return err
+	}
+	if binding.Validator == nil {
+		return nil
+	}
+
+	return binding.Validator.ValidateStruct(val)
+}
+
+// writeBody 使用协商的codec序列化v并写入响应
+// 如果codec无法序列化v, 例如使用protobuf序列化非proto.Message的错误信息, 则使用json
+func writeBody(c *gin.Context, status int, v interface{}) error {
+	codec := responseCodec(c)
+	data, err := codec.Marshal(v)
+	if err != nil {
+		if codec == defaultCodec() {
+			return err
+		}
+		codec = defaultCodec()
+		if data, err = codec.Marshal(v); err != nil {
+			return err
+		}
+	}
+	c.Data(status, codec.ContentType(), data)
+
+	return nil
+}
@@ -0,0 +1,244 @@
+package http
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mangohow/mangokit/encoding"
+	"github.com/mangohow/mangokit/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ProblemContentType RFC 7807错误响应的Content-Type
+	ProblemContentType = "application/problem+json"
+
+	// ErrorInfoType google.rpc.ErrorInfo在Any中的类型
+	ErrorInfoType = "type.googleapis.com/google.rpc.ErrorInfo"
+
+	// ErrorCodeKey google.rpc.ErrorInfo的metadata中保存业务错误码的key
+	ErrorCodeKey = "code"
+)
+
+// problemMembers RFC 7807中定义的成员以及mangokit添加的reason和code, Metadata中的同名key会被忽略
+var problemMembers = map[string]struct{}{
+	"type": {}, "title": {}, "status": {}, "detail": {}, "instance": {}, "reason": {}, "code": {},
+}
+
+// ProblemEncodeErrorFunc 使用RFC 7807 application/problem+json格式返回错误
+// reason和code作为扩展成员返回, Metadata中的每一项也作为扩展成员返回
+func ProblemEncodeErrorFunc(ctx *gin.Context, err error, log *logrus.Logger) {
+	e := errors.Convert(err)
+
+	problem := make(map[string]interface{}, len(e.Metadata())+7)
+	for k, v := range e.Metadata() {
+		if _, ok := problemMembers[k]; !ok {
+			problem[k] = v
+		}
+	}
+	problem["type"] = "about:blank"
+	problem["title"] = http.StatusText(int(e.HttpStatus()))
+	problem["status"] = e.HttpStatus()
+	problem["detail"] = e.Message()
+	problem["instance"] = ctx.Request.URL.Path
+	problem["reason"] = e.Reason()
+	problem["code"] = e.Code()
+
+	writeJSONError(ctx, int(e.HttpStatus()), ProblemContentType, problem, log)
+	log.WithContext(ctx.Request.Context()).Error(e.Error())
+}
+
+// rpcStatus google.rpc.Status的json格式, details中只包含一个google.rpc.ErrorInfo
+type rpcStatus struct {
+	Code    int32       `json:"code"`
+	Message string      `json:"message"`
+	Details []errorInfo `json:"details,omitempty"`
+}
+
+type errorInfo struct {
+	Type     string            `json:"@type"`
+	Reason   string            `json:"reason"`
+	Domain   string            `json:"domain,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// StatusEncodeErrorFunc 使用google.rpc.Status格式返回错误, code为响应码对应的gRPC状态码
+// reason和Metadata放在google.rpc.ErrorInfo中, 业务错误码保存在metadata的ErrorCodeKey中, domain为请求的Host
+func StatusEncodeErrorFunc(ctx *gin.Context, err error, log *logrus.Logger) {
+	e := errors.Convert(err)
+
+	metadata := make(map[string]string, len(e.Metadata())+1)
+	for k, v := range e.Metadata() {
+		metadata[k] = v
+	}
+	metadata[ErrorCodeKey] = strconv.Itoa(int(e.Code()))
+
+	writeJSONError(ctx, int(e.HttpStatus()), "application/json", rpcStatus{
+		Code:    grpcCode(int(e.HttpStatus())),
+		Message: e.Message(),
+		Details: []errorInfo{{
+			Type:     ErrorInfoType,
+			Reason:   e.Reason(),
+			Domain:   ctx.Request.Host,
+			Metadata: metadata,
+		}},
+	}, log)
+	log.WithContext(ctx.Request.Context()).Error(e.Error())
+}
+
+// WithProblemDetails 错误响应使用RFC 7807 application/problem+json格式
+func WithProblemDetails() Option {
+	return WithEncodeErrorFunc(ProblemEncodeErrorFunc)
+}
+
+// WithStatusErrors 错误响应使用google.rpc.Status格式
+func WithStatusErrors() Option {
+	return WithEncodeErrorFunc(StatusEncodeErrorFunc)
+}
+
+func writeJSONError(ctx *gin.Context, status int, contentType string, v interface{}, log *logrus.Logger) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.WithContext(ctx.Request.Context()).Errorf("encode error response failed, %v", err)
+		ctx.Status(status)
+		return
+	}
+	ctx.Data(status, contentType, data)
+}
+
+// grpcCode http响应码对应的gRPC状态码, 参考google.rpc.Code中的说明
+func grpcCode(status int) int32 {
+	switch status {
+	case http.StatusOK:
+		return 0
+	case 499:
+		return 1 // CANCELLED
+	case http.StatusBadRequest:
+		return 3 // INVALID_ARGUMENT
+	case http.StatusGatewayTimeout:
+		return 4 // DEADLINE_EXCEEDED
+	case http.StatusNotFound:
+		return 5 // NOT_FOUND
+	case http.StatusConflict:
+		return 6 // ALREADY_EXISTS
+	case http.StatusForbidden:
+		return 7 // PERMISSION_DENIED
+	case http.StatusTooManyRequests:
+		return 8 // RESOURCE_EXHAUSTED
+	case http.StatusPreconditionFailed:
+		return 9 // FAILED_PRECONDITION
+	case http.StatusNotImplemented:
+		return 12 // UNIMPLEMENTED
+	case http.StatusInternalServerError:
+		return 13 // INTERNAL
+	case http.StatusServiceUnavailable:
+		return 14 // UNAVAILABLE
+	case http.StatusUnauthorized:
+		return 16 // UNAUTHENTICATED
+	}
+
+	return 2 // UNKNOWN
+}
+
+// decodeError 根据响应格式将服务端返回的错误解析为errors.Error, 响应码作为错误的HttpStatus
+// 支持默认的 {"error": {...}}, application/problem+json 以及 google.rpc.Status 三种格式
+// 响应体不是以上格式时(例如网关返回的错误页面), 返回UnknownReason错误, message为响应码描述
+func decodeError(header http.Header, codec encoding.Codec, status int, data []byte) error {
+	if len(data) > 0 {
+		if mt, _, _ := mime.ParseMediaType(header.Get("Content-Type")); mt == ProblemContentType {
+			if e := decodeProblem(status, data); e != nil {
+				return e
+			}
+		} else if e := decodeEnvelope(codec, status, data); e != nil {
+			return e
+		} else if e := decodeStatus(status, data); e != nil {
+			return e
+		}
+	}
+
+	return errors.New(errors.UnknownCode, int32(status), errors.UnknownReason, http.StatusText(status))
+}
+
+func decodeEnvelope(codec encoding.Codec, status int, data []byte) errors.Error {
+	var r struct {
+		Error *errors.ErrorImpl `json:"error"`
+	}
+	if codec.Unmarshal(data, &r) != nil || r.Error == nil || r.Error.Reason_ == "" {
+		return nil
+	}
+
+	return withMetadata(errors.New(r.Error.Code_, int32(status), r.Error.Reason_, r.Error.Message_), r.Error.Metadata_)
+}
+
+func decodeProblem(status int, data []byte) errors.Error {
+	var members map[string]json.RawMessage
+	if json.Unmarshal(data, &members) != nil {
+		return nil
+	}
+
+	var (
+		reason, detail string
+		code           int32 = errors.UnknownCode
+	)
+	_ = json.Unmarshal(members["detail"], &detail)
+	if json.Unmarshal(members["reason"], &reason) != nil || reason == "" {
+		reason = errors.UnknownReason
+	}
+	if raw, ok := members["code"]; ok {
+		_ = json.Unmarshal(raw, &code)
+	}
+
+	var metadata map[string]string
+	for k, raw := range members {
+		if _, ok := problemMembers[k]; ok {
+			continue
+		}
+		var v string
+		if json.Unmarshal(raw, &v) != nil {
+			v = string(raw)
+		}
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		metadata[k] = v
+	}
+
+	return withMetadata(errors.New(code, int32(status), reason, detail), metadata)
+}
+
+func decodeStatus(status int, data []byte) errors.Error {
+	var s rpcStatus
+	if json.Unmarshal(data, &s) != nil {
+		return nil
+	}
+	for _, detail := range s.Details {
+		if detail.Type != ErrorInfoType || detail.Reason == "" {
+			continue
+		}
+		code := int32(errors.UnknownCode)
+		if c, err := strconv.ParseInt(detail.Metadata[ErrorCodeKey], 10, 32); err == nil {
+			code = int32(c)
+			delete(detail.Metadata, ErrorCodeKey)
+		}
+
+		return withMetadata(errors.New(code, int32(status), detail.Reason, s.Message), detail.Metadata)
+	}
+
+	return nil
+}
+
+// withMetadata 将解析得到的metadata添加到错误中
+func withMetadata(e errors.Error, metadata map[string]string) errors.Error {
+	if len(metadata) == 0 {
+		return e
+	}
+	c := errors.Clone(e)
+	for k, v := range metadata {
+		c = c.WithMetadata(k, v)
+	}
+
+	return c
+}
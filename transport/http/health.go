@@ -0,0 +1,184 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	DefaultLivenessPath  = "/healthz"
+	DefaultReadinessPath = "/readyz"
+
+	defaultHealthCheckTimeout = 3 * time.Second
+
+	HealthStatusUp   = "UP"
+	HealthStatusDown = "DOWN"
+)
+
+// HealthChecker 健康检查函数, 返回错误表示组件不可用
+type HealthChecker func(ctx context.Context) error
+
+// Pinger 可以进行健康检查的组件, 例如 *sql.DB, *sqlx.DB
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// PingChecker 使用PingContext进行健康检查
+func PingChecker(p Pinger) HealthChecker {
+	return p.PingContext
+}
+
+// ClientChecker 通过client请求下游服务的path进行健康检查, 响应码为2xx时表示下游服务可用
+func ClientChecker(c *Client, path string) HealthChecker {
+	return func(ctx context.Context) error {
+		status, err := c.Invoke(ctx, http.MethodGet, path, nil, nil)
+		if err != nil {
+			return err
+		}
+		if status < 200 || status >= 300 {
+			return fmt.Errorf("unexpected status %d", status)
+		}
+
+		return nil
+	}
+}
+
+// HealthCheckResult 单个组件的检查结果
+type HealthCheckResult struct {
+	Status    string  `json:"status"`
+	Latency   string  `json:"latency"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// HealthResponse 健康检查响应
+type HealthResponse struct {
+	Status string                       `json:"status"`
+	Checks map[string]HealthCheckResult `json:"checks,omitempty"`
+}
+
+type namedChecker struct {
+	name    string
+	checker HealthChecker
+}
+
+type health struct {
+	livenessPath  string
+	readinessPath string
+	timeout       time.Duration
+	drainDelay    time.Duration
+
+	mu       sync.RWMutex
+	checkers []namedChecker
+	draining int32
+}
+
+func newHealth() *health {
+	return &health{
+		livenessPath:  DefaultLivenessPath,
+		readinessPath: DefaultReadinessPath,
+		timeout:       defaultHealthCheckTimeout,
+	}
+}
+
+func (h *health) register(name string, checker HealthChecker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i := range h.checkers {
+		if h.checkers[i].name == name {
+			h.checkers[i].checker = checker
+			return
+		}
+	}
+	h.checkers = append(h.checkers, namedChecker{name: name, checker: checker})
+}
+
+// liveness 进程能够处理请求即表示存活, 不检查依赖的组件
+func (h *health) liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthResponse{Status: HealthStatusUp})
+}
+
+// readiness 并发执行所有检查, 任意一个检查失败或者Server正在停止时返回503
+func (h *health) readiness(c *gin.Context) {
+	if atomic.LoadInt32(&h.draining) == 1 {
+		c.JSON(http.StatusServiceUnavailable, HealthResponse{Status: HealthStatusDown})
+		return
+	}
+
+	h.mu.RLock()
+	checkers := make([]namedChecker, len(h.checkers))
+	copy(checkers, h.checkers)
+	h.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	results := make([]HealthCheckResult, len(checkers))
+	wg := sync.WaitGroup{}
+	for i := range checkers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, checkers[i].checker)
+		}(i)
+	}
+	wg.Wait()
+
+	resp := HealthResponse{
+		Status: HealthStatusUp,
+		Checks: make(map[string]HealthCheckResult, len(checkers)),
+	}
+	for i, r := range results {
+		if r.Status != HealthStatusUp {
+			resp.Status = HealthStatusDown
+		}
+		resp.Checks[checkers[i].name] = r
+	}
+
+	status := http.StatusOK
+	if resp.Status != HealthStatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, resp)
+}
+
+func runCheck(ctx context.Context, checker HealthChecker) (r HealthCheckResult) {
+	start := time.Now()
+	defer func() {
+		if e := recover(); e != nil {
+			r.Status = HealthStatusDown
+			r.Error = fmt.Sprintf("panic: %v", e)
+		}
+		latency := time.Since(start)
+		r.Latency = latency.String()
+		r.LatencyMs = float64(latency) / float64(time.Millisecond)
+	}()
+
+	r.Status = HealthStatusUp
+	if err := checker(ctx); err != nil {
+		r.Status = HealthStatusDown
+		r.Error = err.Error()
+	}
+
+	return
+}
+
+// drain 标记Server正在停止, 并等待drainDelay使负载均衡感知到readiness的变化
+func (h *health) drain(ctx context.Context) {
+	if !atomic.CompareAndSwapInt32(&h.draining, 0, 1) || h.drainDelay <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(h.drainDelay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,257 @@
+package http
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	stderr "errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	errTokenMalformed = stderr.New("token is malformed")
+	errTokenSignature = stderr.New("token signature is invalid")
+	errTokenExpired   = stderr.New("token is expired")
+	errTokenNotValid  = stderr.New("token is not valid yet")
+)
+
+// JWTKeyFunc 根据token header中的alg和kid返回验证签名使用的key
+// HS256/HS384/HS512使用[]byte, RS256/RS384/RS512使用*rsa.PublicKey
+type JWTKeyFunc func(alg, kid string) (interface{}, error)
+
+// StaticJWTKey 所有token使用同一个key验证
+func StaticJWTKey(key interface{}) JWTKeyFunc {
+	return func(alg, kid string) (interface{}, error) {
+		return key, nil
+	}
+}
+
+// Claims JWT中的claims
+type Claims map[string]interface{}
+
+// Subject sub claim
+func (c Claims) Subject() string {
+	return c.GetString("sub")
+}
+
+// Issuer iss claim
+func (c Claims) Issuer() string {
+	return c.GetString("iss")
+}
+
+// Audience aud claim, 可以是字符串或字符串数组
+func (c Claims) Audience() []string {
+	switch v := c["aud"].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		aud := make([]string, 0, len(v))
+		for _, a := range v {
+			if s, ok := a.(string); ok {
+				aud = append(aud, s)
+			}
+		}
+		return aud
+	case []string:
+		return v
+	}
+	return nil
+}
+
+// ExpiresAt exp claim, 不存在时返回零值
+func (c Claims) ExpiresAt() time.Time {
+	return c.GetTime("exp")
+}
+
+// NotBefore nbf claim, 不存在时返回零值
+func (c Claims) NotBefore() time.Time {
+	return c.GetTime("nbf")
+}
+
+// IssuedAt iat claim, 不存在时返回零值
+func (c Claims) IssuedAt() time.Time {
+	return c.GetTime("iat")
+}
+
+// GetString 获取字符串类型的claim
+func (c Claims) GetString(key string) string {
+	s, _ := c[key].(string)
+	return s
+}
+
+// GetTime 获取以unix秒表示的时间类型的claim
+func (c Claims) GetTime(key string) time.Time {
+	switch v := c[key].(type) {
+	case float64:
+		return time.Unix(0, int64(v*float64(time.Second)))
+	case int64:
+		return time.Unix(v, 0)
+	case int:
+		return time.Unix(int64(v), 0)
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return time.Unix(0, int64(f*float64(time.Second)))
+		}
+	}
+	return time.Time{}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+func jwtHash(alg string) (crypto.Hash, bool) {
+	switch alg {
+	case "HS256", "RS256":
+		return crypto.SHA256, true
+	case "HS384", "RS384":
+		return crypto.SHA384, true
+	case "HS512", "RS512":
+		return crypto.SHA512, true
+	}
+	return 0, false
+}
+
+// SignJWT 使用alg签发token, HS算法的key为[]byte, RS算法的key为*rsa.PrivateKey
+func SignJWT(alg string, key interface{}, claims Claims) (string, error) {
+	return SignJWTWithKid(alg, "", key, claims)
+}
+
+// SignJWTWithKid 签发token并在header中设置kid, 用于密钥轮换
+func SignJWTWithKid(alg, kid string, key interface{}, claims Claims) (string, error) {
+	header, err := json.Marshal(jwtHeader{Alg: alg, Typ: "JWT", Kid: kid})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	hash, ok := jwtHash(alg)
+	if !ok {
+		return "", fmt.Errorf("unsupported jwt alg %q", alg)
+	}
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		if alg[0] != 'H' {
+			return "", fmt.Errorf("key type %T mismatch alg %s", key, alg)
+		}
+		mac := hmac.New(hash.New, k)
+		mac.Write([]byte(input))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		if alg[0] != 'R' {
+			return "", fmt.Errorf("key type %T mismatch alg %s", key, alg)
+		}
+		h := hash.New()
+		h.Write([]byte(input))
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, k, hash, h.Sum(nil)); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("unsupported jwt key type %T", key)
+	}
+
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// ParseJWT 验证token的签名并解析claims, 不校验exp等时间相关的claim
+// 签名算法必须与key的类型匹配, 不接受alg为none的token
+func ParseJWT(token string, keyFunc JWTKeyFunc) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errTokenMalformed
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errTokenMalformed
+	}
+	hash, ok := jwtHash(header.Alg)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported alg %q", errTokenSignature, header.Alg)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errTokenMalformed
+	}
+	key, err := keyFunc(header.Alg, header.Kid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errTokenSignature, err)
+	}
+
+	input := parts[0] + "." + parts[1]
+	switch k := key.(type) {
+	case []byte:
+		if header.Alg[0] != 'H' {
+			return nil, errTokenSignature
+		}
+		mac := hmac.New(hash.New, k)
+		mac.Write([]byte(input))
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return nil, errTokenSignature
+		}
+	case *rsa.PublicKey:
+		if header.Alg[0] != 'R' {
+			return nil, errTokenSignature
+		}
+		h := hash.New()
+		h.Write([]byte(input))
+		if rsa.VerifyPKCS1v15(k, hash, h.Sum(nil), sig) != nil {
+			return nil, errTokenSignature
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported key type %T", errTokenSignature, key)
+	}
+
+	claims := make(Claims)
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return nil, errTokenMalformed
+	}
+
+	return claims, nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// validateClaims 校验exp, nbf, iss以及aud
+func validateClaims(claims Claims, o *authOptions) error {
+	now := time.Now()
+	if exp := claims.ExpiresAt(); !exp.IsZero() && now.After(exp.Add(o.leeway)) {
+		return errTokenExpired
+	}
+	if nbf := claims.NotBefore(); !nbf.IsZero() && now.Add(o.leeway).Before(nbf) {
+		return errTokenNotValid
+	}
+	if o.issuer != "" && claims.Issuer() != o.issuer {
+		return fmt.Errorf("unexpected issuer %q", claims.Issuer())
+	}
+	if o.audience != "" {
+		for _, aud := range claims.Audience() {
+			if aud == o.audience {
+				return nil
+			}
+		}
+		return fmt.Errorf("audience %q not allowed", claims.Audience())
+	}
+
+	return nil
+}
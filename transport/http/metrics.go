@@ -0,0 +1,147 @@
+package http
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mangohow/mangokit/errors"
+	"github.com/mangohow/mangokit/metrics"
+)
+
+const DefaultMetricsPath = "/metrics"
+
+type serverMetrics struct {
+	requests *metrics.CounterVec
+	latency  *metrics.HistogramVec
+	inflight *metrics.GaugeVec
+}
+
+func newServerMetrics(reg *metrics.Registry) *serverMetrics {
+	return &serverMetrics{
+		requests: reg.NewCounterVec("mangokit_http_server_requests_total",
+			"Total number of HTTP requests handled by the server.",
+			"operation", "method", "route", "code", "status"),
+		latency: reg.NewHistogramVec("mangokit_http_server_request_duration_seconds",
+			"Latency of HTTP requests handled by the server in seconds.", nil,
+			"operation", "method", "route"),
+		inflight: reg.NewGaugeVec("mangokit_http_server_requests_in_flight",
+			"Number of HTTP requests currently handled by the server.",
+			"operation", "method", "route"),
+	}
+}
+
+// observe 记录一次请求, 返回请求结束时调用的函数, err为nil时status作为status标签
+func (m *serverMetrics) observe(operation, method, route string) func(status int, err error) {
+	inflight := m.inflight.With(operation, method, route)
+	inflight.Inc()
+	start := time.Now()
+
+	return func(status int, err error) {
+		m.latency.With(operation, method, route).Observe(time.Since(start).Seconds())
+		inflight.Dec()
+		code, status := metricCode(err, status)
+		m.requests.With(operation, method, route, code, strconv.Itoa(status)).Inc()
+	}
+}
+
+// observePanic handler发生panic且没有被Recovery捕获时记录的错误
+var observePanic = errors.New(errors.UnknownCode, errors.DefaultStatus, PanicReason, "panic")
+
+// Metrics 服务端指标中间件, 记录请求数量, 耗时以及正在处理的请求数量
+// 请求数量使用errors.Error的Code()和HttpStatus()作为code和status标签
+// 中间件只能记录参数解析成功的请求, WithMetrics在处理请求的最外层记录指标, 包括参数解析失败的请求
+// Server使用WithMetrics开启了相同Registry的指标时, 中间件直接调用handler, 避免重复记录
+func Metrics(reg *metrics.Registry) Middleware {
+	m := newServerMetrics(reg)
+
+	return func(ctx context.Context, req interface{}, handler Handler) (resp interface{}, err error) {
+		var operation, method, route string
+		tr, ok := FromServerContext(ctx)
+		if ok && tr.metrics == reg {
+			return handler(ctx, req)
+		}
+		if ok {
+			operation, method, route = tr.Operation(), tr.Method(), tr.PathTemplate()
+		}
+
+		done := m.observe(operation, method, route)
+		finished := false
+		defer func() {
+			if !finished {
+				done(errors.DefaultStatus, observePanic)
+				return
+			}
+			status := StatusOK
+			if ok {
+				status = tr.StatusCode()
+			}
+			done(status, err)
+		}()
+
+		resp, err = handler(ctx, req)
+		finished = true
+
+		return resp, err
+	}
+}
+
+// metricCode 获取错误码和响应码标签, 请求成功时错误码为0
+func metricCode(err error, status int) (string, int) {
+	if err == nil {
+		return "0", status
+	}
+	e := errors.Convert(err)
+
+	return strconv.Itoa(int(e.Code())), int(e.HttpStatus())
+}
+
+type clientMetrics struct {
+	requests *metrics.CounterVec
+	latency  *metrics.HistogramVec
+	inflight *metrics.GaugeVec
+}
+
+func newClientMetrics(reg *metrics.Registry) *clientMetrics {
+	return &clientMetrics{
+		requests: reg.NewCounterVec("mangokit_http_client_requests_total",
+			"Total number of HTTP requests sent by the client.",
+			"operation", "method", "route", "code", "status"),
+		latency: reg.NewHistogramVec("mangokit_http_client_request_duration_seconds",
+			"Latency of HTTP requests sent by the client in seconds.", nil,
+			"operation", "method", "route"),
+		inflight: reg.NewGaugeVec("mangokit_http_client_requests_in_flight",
+			"Number of HTTP requests currently sent by the client.",
+			"operation", "method", "route"),
+	}
+}
+
+// observe 记录一次请求, 返回请求结束时调用的函数
+// route为生成代码中的路由模板, 为空时不使用实际的请求路径, 避免标签数量过多
+func (m *clientMetrics) observe(operation, method, route string) func(status int, err error) {
+	inflight := m.inflight.With(operation, method, route)
+	inflight.Inc()
+	start := time.Now()
+
+	return func(status int, err error) {
+		m.latency.With(operation, method, route).Observe(time.Since(start).Seconds())
+		inflight.Dec()
+		code := "0"
+		if err != nil {
+			code, status = metricCode(err, status)
+			if !errors.IsError(err) {
+				// 网络错误等没有收到响应
+				status = 0
+			}
+		}
+		m.requests.With(operation, method, route, code, strconv.Itoa(status)).Inc()
+	}
+}
+
+func metricsHandler(reg *metrics.Registry) gin.HandlerFunc {
+	h := reg.Handler()
+	return func(c *gin.Context) {
+		h.ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -2,6 +2,8 @@ package http
 
 import (
 	"context"
+	"net/http"
+	"strings"
 )
 
 type Handler func(ctx context.Context, req interface{}) (resp interface{}, err error)
@@ -11,12 +13,86 @@ type Middleware func(ctx context.Context, req interface{}, handler Handler) (int
 type methodHandler func(srv interface{}, ctx context.Context, dec func(interface{}) error, middleware Middleware) (interface{}, error)
 
 type ServiceDesc struct {
+	ServiceName string
 	HandlerType interface{}
 	Methods     []MethodDesc
 }
 
 type MethodDesc struct {
-	Method  string
-	Path    string
-	Handler methodHandler
+	Name       string
+	FullMethod string
+	Method     string
+	Path       string
+	Handler    methodHandler
+}
+
+// RegisterOption 注册service时的选项
+type RegisterOption func(o *registerOptions)
+
+type registerOptions struct {
+	middlewares       []Middleware
+	methodMiddlewares map[string][]Middleware
+	methodStatus      map[string]int
+}
+
+func (o *registerOptions) statusCode(name string) int {
+	if status, ok := o.methodStatus[name]; ok {
+		return status
+	}
+
+	return http.StatusOK
+}
+
+// WithServiceMiddleware 为当前注册的service的所有方法添加中间件
+func WithServiceMiddleware(middleware ...Middleware) RegisterOption {
+	return func(o *registerOptions) {
+		o.middlewares = append(o.middlewares, middleware...)
+	}
+}
+
+// WithMethodMiddleware 为当前注册的service中名称为name的方法添加中间件, name为MethodDesc中的Name
+func WithMethodMiddleware(name string, middleware ...Middleware) RegisterOption {
+	return func(o *registerOptions) {
+		if o.methodMiddlewares == nil {
+			o.methodMiddlewares = make(map[string][]Middleware)
+		}
+		o.methodMiddlewares[name] = append(o.methodMiddlewares[name], middleware...)
+	}
+}
+
+// WithMethodStatus 设置名称为name的方法成功时的响应码, 例如创建资源时返回201, 无响应体时返回204
+func WithMethodStatus(name string, status int) RegisterOption {
+	return func(o *registerOptions) {
+		if o.methodStatus == nil {
+			o.methodStatus = make(map[string]int)
+		}
+		o.methodStatus[name] = status
+	}
+}
+
+type groupMiddleware struct {
+	prefix      string
+	middlewares []Middleware
+}
+
+type groupAuth struct {
+	prefix string
+	auths  []Authenticator
+}
+
+// hasPathPrefix 按路径段匹配前缀, /admin 可以匹配 /admin 和 /admin/user, 但不匹配 /administrator
+func hasPathPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return true
+	}
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+
+	return len(path) == len(prefix) || path[len(prefix)] == '/'
+}
+
+func methodKey(method, path string) string {
+	return strings.ToUpper(method) + " " + path
 }
@@ -0,0 +1,353 @@
+package http
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mangohow/mangokit/errors"
+	"github.com/mangohow/mangokit/proc"
+)
+
+const (
+	// RateLimitReason 请求频率超过限制时错误的reason, 响应码为429
+	RateLimitReason = "RATE_LIMITED"
+	// ConcurrencyLimitReason 并发请求数超过限制时错误的reason, 响应码为503
+	ConcurrencyLimitReason = "CONCURRENCY_LIMITED"
+	// LoadSheddingReason 自适应限流拒绝请求时错误的reason, 响应码为503
+	LoadSheddingReason = "LOAD_SHEDDING"
+)
+
+// LimitKeyFunc 从请求中获取限流的key, key相同的请求共享同一个限流器
+type LimitKeyFunc func(ctx context.Context) string
+
+// KeyGlobal 所有请求共享同一个限流器
+func KeyGlobal() LimitKeyFunc {
+	return func(ctx context.Context) string {
+		return ""
+	}
+}
+
+// KeyClientIP 按照客户端IP限流, IP的获取方式与gin.Context.ClientIP一致
+func KeyClientIP() LimitKeyFunc {
+	return func(ctx context.Context) string {
+		if c, ok := ctx.Value("gin-ctx").(*gin.Context); ok {
+			return c.ClientIP()
+		}
+		return ""
+	}
+}
+
+// KeyHeader 按照请求头限流, 例如 KeyHeader("X-Api-Key")
+func KeyHeader(name string) LimitKeyFunc {
+	return func(ctx context.Context) string {
+		if tr, ok := FromServerContext(ctx); ok {
+			return tr.RequestHeader().Get(name)
+		}
+		return ""
+	}
+}
+
+// KeyOperation 按照接口限流, 使用proto中定义的完整方法名, 不存在时使用请求方法和路由模板
+func KeyOperation() LimitKeyFunc {
+	return func(ctx context.Context) string {
+		if tr, ok := FromServerContext(ctx); ok {
+			if tr.Operation() != "" {
+				return tr.Operation()
+			}
+			return tr.Method() + " " + tr.PathTemplate()
+		}
+		return ""
+	}
+}
+
+type limitOptions struct {
+	key LimitKeyFunc
+}
+
+type LimitOption func(o *limitOptions)
+
+// WithLimitKey 设置限流的key, 默认为KeyGlobal
+func WithLimitKey(fn LimitKeyFunc) LimitOption {
+	return func(o *limitOptions) {
+		o.key = fn
+	}
+}
+
+func newLimitOptions(opts []LimitOption) *limitOptions {
+	o := &limitOptions{key: KeyGlobal()}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// setRetryAfter 设置Retry-After响应头, 单位为秒, 至少为1
+func setRetryAfter(ctx context.Context, d time.Duration) {
+	tr, ok := FromServerContext(ctx)
+	if !ok {
+		return
+	}
+	seconds := int64(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	tr.ReplyHeader().Set("Retry-After", strconv.FormatInt(seconds, 10))
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimit 令牌桶限流, 每秒生成rate个令牌, 最多积累burst个
+// 超过限制时返回errors.TooManyRequests, 并通过Retry-After告知客户端下一个令牌的等待时间
+// rate必须大于0, burst至少为1, 否则panic
+func RateLimit(rate float64, burst int, opts ...LimitOption) Middleware {
+	if !(rate > 0) {
+		panic("http: rate limit rate must be positive")
+	}
+	if burst < 1 {
+		panic("http: rate limit burst must be at least 1")
+	}
+	o := newLimitOptions(opts)
+	var (
+		mu        sync.Mutex
+		buckets   = make(map[string]*tokenBucket)
+		lastClean = time.Now()
+	)
+	take := func(key string, now time.Time) (bool, time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// key较多时定期清理已经装满的桶, 装满的桶与新建的桶等价
+		if len(buckets) > 1024 && now.Sub(lastClean) > time.Minute {
+			for k, b := range buckets {
+				if b.tokens+now.Sub(b.last).Seconds()*rate >= float64(burst) {
+					delete(buckets, k)
+				}
+			}
+			lastClean = now
+		}
+
+		b, ok := buckets[key]
+		if !ok {
+			b = &tokenBucket{tokens: float64(burst), last: now}
+			buckets[key] = b
+		}
+		b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			return true, 0
+		}
+
+		return false, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+
+	return func(ctx context.Context, req interface{}, handler Handler) (interface{}, error) {
+		if ok, wait := take(o.key(ctx), time.Now()); !ok {
+			setRetryAfter(ctx, wait)
+			return nil, errors.TooManyRequests(http.StatusTooManyRequests, RateLimitReason, "too many requests")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// ConcurrencyLimit 限制同时处理的请求数量, 超过max时返回errors.ServiceUnavailable
+func ConcurrencyLimit(max int, opts ...LimitOption) Middleware {
+	o := newLimitOptions(opts)
+	var (
+		mu       sync.Mutex
+		inflight = make(map[string]int)
+	)
+
+	return func(ctx context.Context, req interface{}, handler Handler) (interface{}, error) {
+		key := o.key(ctx)
+		mu.Lock()
+		if inflight[key] >= max {
+			mu.Unlock()
+			setRetryAfter(ctx, time.Second)
+			return nil, errors.ServiceUnavailable(http.StatusServiceUnavailable, ConcurrencyLimitReason, "too many concurrent requests")
+		}
+		inflight[key]++
+		mu.Unlock()
+
+		defer func() {
+			mu.Lock()
+			if inflight[key]--; inflight[key] <= 0 {
+				delete(inflight, key)
+			}
+			mu.Unlock()
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+type adaptiveOptions struct {
+	minLimit     int
+	maxLimit     int
+	tolerance    float64
+	window       time.Duration
+	cpuThreshold float64
+	cpuUsage     func() float64
+}
+
+type AdaptiveOption func(o *adaptiveOptions)
+
+// WithLimitRange 设置自适应并发上限的范围, 默认为[10, 1000], 初始值为最小值
+func WithLimitRange(min, max int) AdaptiveOption {
+	return func(o *adaptiveOptions) {
+		o.minLimit = min
+		o.maxLimit = max
+	}
+}
+
+// WithLatencyTolerance 请求耗时超过最小耗时的tolerance倍时降低并发上限, 默认为2
+func WithLatencyTolerance(tolerance float64) AdaptiveOption {
+	return func(o *adaptiveOptions) {
+		o.tolerance = tolerance
+	}
+}
+
+// WithLatencyWindow 设置统计最小耗时的窗口, 默认为30s, 最小耗时会在两个窗口后过期以适应负载变化
+func WithLatencyWindow(window time.Duration) AdaptiveOption {
+	return func(o *adaptiveOptions) {
+		o.window = window
+	}
+}
+
+// WithCPUThreshold CPU使用率达到threshold(取值为[0, 1])时降低并发上限, 默认不检查CPU
+func WithCPUThreshold(threshold float64) AdaptiveOption {
+	return func(o *adaptiveOptions) {
+		o.cpuThreshold = threshold
+	}
+}
+
+// WithCPUSampler 设置CPU使用率的采样函数, 默认为proc.NewCPUSampler
+func WithCPUSampler(fn func() float64) AdaptiveOption {
+	return func(o *adaptiveOptions) {
+		o.cpuUsage = fn
+	}
+}
+
+// adaptiveLimiter 使用AIMD算法调整并发上限: 请求耗时和CPU正常并且并发接近上限时加1, 否则乘以0.9
+type adaptiveLimiter struct {
+	o *adaptiveOptions
+
+	mu        sync.Mutex
+	limit     float64
+	inflight  int
+	minRTT    time.Duration
+	prevRTT   time.Duration
+	windowEnd time.Time
+
+	cpuAt time.Time
+	cpu   float64
+}
+
+// AdaptiveLimit 自适应并发限制, 在请求耗时升高或CPU使用率过高时自动降低并发上限, 拒绝的请求返回errors.ServiceUnavailable
+func AdaptiveLimit(opts ...AdaptiveOption) Middleware {
+	o := &adaptiveOptions{
+		minLimit:  10,
+		maxLimit:  1000,
+		tolerance: 2,
+		window:    30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.cpuThreshold > 0 && o.cpuUsage == nil {
+		o.cpuUsage = proc.NewCPUSampler()
+	}
+	l := newAdaptiveLimiter(o)
+
+	return func(ctx context.Context, req interface{}, handler Handler) (interface{}, error) {
+		if !l.acquire() {
+			setRetryAfter(ctx, time.Second)
+			return nil, errors.ServiceUnavailable(http.StatusServiceUnavailable, LoadSheddingReason, "server overloaded")
+		}
+		start := time.Now()
+		defer func() {
+			l.release(time.Since(start))
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+func newAdaptiveLimiter(o *adaptiveOptions) *adaptiveLimiter {
+	return &adaptiveLimiter{
+		o:     o,
+		limit: float64(o.minLimit),
+	}
+}
+
+func (l *adaptiveLimiter) acquire() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inflight >= int(l.limit) {
+		return false
+	}
+	l.inflight++
+	return true
+}
+
+func (l *adaptiveLimiter) release(rtt time.Duration) {
+	now := time.Now()
+	overloaded := l.cpuOverloaded(now)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	inflight := l.inflight
+	l.inflight--
+
+	if now.After(l.windowEnd) {
+		l.prevRTT, l.minRTT = l.minRTT, 0
+		l.windowEnd = now.Add(l.o.window)
+	}
+	if l.minRTT == 0 || rtt < l.minRTT {
+		l.minRTT = rtt
+	}
+	baseline := l.minRTT
+	if l.prevRTT > 0 && l.prevRTT < baseline {
+		baseline = l.prevRTT
+	}
+
+	if overloaded || float64(rtt) > float64(baseline)*l.o.tolerance {
+		l.limit = math.Max(float64(l.o.minLimit), l.limit*0.9)
+	} else if float64(inflight) >= l.limit/2 {
+		l.limit = math.Min(float64(l.o.maxLimit), l.limit+1)
+	}
+}
+
+// cpuOverloaded 最多每100ms采样一次CPU使用率
+func (l *adaptiveLimiter) cpuOverloaded(now time.Time) bool {
+	if l.o.cpuThreshold <= 0 {
+		return false
+	}
+
+	l.mu.Lock()
+	if now.Sub(l.cpuAt) < 100*time.Millisecond {
+		cpu := l.cpu
+		l.mu.Unlock()
+		return cpu >= l.o.cpuThreshold
+	}
+	l.cpuAt = now
+	l.mu.Unlock()
+
+	cpu := l.o.cpuUsage()
+	l.mu.Lock()
+	l.cpu = cpu
+	l.mu.Unlock()
+
+	return cpu >= l.o.cpuThreshold
+}
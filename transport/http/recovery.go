@@ -0,0 +1,85 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/mangohow/mangokit/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// PanicReason handler发生panic时错误的默认reason
+const PanicReason = "PANIC"
+
+type recoveryOptions struct {
+	reason  string
+	log     *logrus.Logger
+	handler func(ctx context.Context, req, p interface{}) error
+}
+
+type RecoveryOption func(*recoveryOptions)
+
+// WithRecoveryReason 设置panic转换成的错误的reason
+func WithRecoveryReason(reason string) RecoveryOption {
+	return func(o *recoveryOptions) {
+		o.reason = reason
+	}
+}
+
+// WithRecoveryLogger 设置输出panic堆栈的logger, 默认为logrus.StandardLogger()
+func WithRecoveryLogger(log *logrus.Logger) RecoveryOption {
+	return func(o *recoveryOptions) {
+		o.log = log
+	}
+}
+
+// WithRecoveryHandler 自定义panic到error的转换, 堆栈仍然会输出到日志
+func WithRecoveryHandler(fn func(ctx context.Context, req, p interface{}) error) RecoveryOption {
+	return func(o *recoveryOptions) {
+		o.handler = fn
+	}
+}
+
+// Recovery 捕获handler及后续中间件中的panic, 记录堆栈并返回errors.InternalServer,
+// 响应仍然是serialize.Response格式, panic的具体内容不会返回给客户端
+func Recovery(opts ...RecoveryOption) Middleware {
+	o := &recoveryOptions{
+		reason: PanicReason,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.log == nil {
+		o.log = logrus.StandardLogger()
+	}
+
+	return func(ctx context.Context, req interface{}, handler Handler) (resp interface{}, err error) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+
+			var operation string
+			if tr, ok := FromServerContext(ctx); ok {
+				operation = tr.Operation()
+			}
+			o.log.WithContext(ctx).Errorf("panic recovered, operation: %s, panic: %v\n%s", operation, p, debug.Stack())
+
+			resp = nil
+			if o.handler != nil {
+				err = o.handler(ctx, req, p)
+				return
+			}
+			err = errors.InternalServerCause(http.StatusInternalServerError, o.reason,
+				http.StatusText(http.StatusInternalServerError), fmt.Errorf("panic: %v", p))
+		}()
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,143 @@
+package http
+
+import (
+	"context"
+	stderr "errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/mangohow/mangokit/errors"
+)
+
+// RetryPolicy 客户端请求的重试策略
+type RetryPolicy struct {
+	// MaxAttempts 最大请求次数, 包括第一次请求, 小于等于1时不重试
+	MaxAttempts int
+	// InitialBackoff 第一次重试前的等待时间
+	InitialBackoff time.Duration
+	// MaxBackoff 重试等待时间的上限
+	MaxBackoff time.Duration
+	// Multiplier 每次重试等待时间的增长倍数
+	Multiplier float64
+	// Jitter 等待时间的随机抖动比例, 取值为[0, 1], 实际等待时间为 backoff * (1 ± Jitter)
+	Jitter float64
+	// RetryableStatus 需要重试的响应码
+	RetryableStatus []int
+	// RetryableReasons 需要重试的错误reason, 从响应的errors.Error中获取
+	RetryableReasons []string
+	// RetryOnNetworkError 是否在网络错误时重试, ctx取消或超时不会重试
+	RetryOnNetworkError bool
+	// AllowNonIdempotent 是否允许重试POST, PATCH等非幂等请求
+	AllowNonIdempotent bool
+}
+
+// DefaultRetryPolicy 默认重试策略, 最多请求3次, 在网络错误, 502, 503, 504时重试幂等请求
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:         3,
+		InitialBackoff:      100 * time.Millisecond,
+		MaxBackoff:          2 * time.Second,
+		Multiplier:          2,
+		Jitter:              0.2,
+		RetryableStatus:     []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+		RetryOnNetworkError: true,
+	}
+}
+
+// WithRetry 设置客户端默认的重试策略, 可以使用RetryCallOption为单次请求设置
+func WithRetry(policy *RetryPolicy) ClientOption {
+	return func(c *config) {
+		c.retry = policy
+	}
+}
+
+type retryCallOption struct {
+	EmptyCallOptions
+	policy *RetryPolicy
+}
+
+func (c retryCallOption) Before(info *BeforeCallInfo) {
+	info.Retry = c.policy
+}
+
+// RetryCallOption 为单次请求设置重试策略, 覆盖客户端默认的重试策略
+// 传入MaxAttempts小于等于1的策略可以禁用该请求的重试
+func RetryCallOption(policy *RetryPolicy) CallOption {
+	return retryCallOption{policy: policy}
+}
+
+// idempotent 根据RFC 7231判断请求方法是否幂等
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// shouldRetry 判断第attempt次请求(从1开始)结束后是否需要重试
+// 请求成功时err为nil, 服务端返回错误响应码时err为errors.Error, 否则为网络错误
+func (p *RetryPolicy) shouldRetry(ctx context.Context, attempt int, method string, status int, err error) bool {
+	if p == nil || attempt >= p.MaxAttempts || ctx.Err() != nil {
+		return false
+	}
+	if !p.AllowNonIdempotent && !idempotent(method) {
+		return false
+	}
+
+	if err == nil {
+		return false
+	}
+	e, ok := err.(errors.Error)
+	if !ok {
+		return p.RetryOnNetworkError && !stderr.Is(err, context.Canceled) && !stderr.Is(err, context.DeadlineExceeded)
+	}
+	for _, s := range p.RetryableStatus {
+		if s == status {
+			return true
+		}
+	}
+	for _, r := range p.RetryableReasons {
+		if r == e.Reason() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// backoff 第attempt次重试前的等待时间
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	d := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		jitter := math.Min(p.Jitter, 1)
+		d *= 1 + jitter*(rand.Float64()*2-1)
+	}
+
+	return time.Duration(d)
+}
+
+// wait 等待重试, ctx结束时返回false
+func (p *RetryPolicy) wait(ctx context.Context, attempt int) bool {
+	d := p.backoff(attempt)
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -2,15 +2,19 @@ package http
 
 import (
 	"context"
-	stderr "errors"
+	stdjson "encoding/json"
 	"net/http"
 	"reflect"
-	"strconv"
-	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mangohow/mangokit/encoding/json"
 	"github.com/mangohow/mangokit/errors"
+	"github.com/mangohow/mangokit/metrics"
 	"github.com/mangohow/mangokit/serialize"
+	"github.com/mangohow/mangokit/tracing"
 	"github.com/sirupsen/logrus"
 )
 
@@ -20,14 +24,36 @@ const (
 )
 
 type Server struct {
-	server *http.Server
-	router *gin.Engine
-	addr   string
+	server  *http.Server
+	router  *gin.Engine
+	rawPath bool
+	addr    string
 
-	log       *logrus.Logger
-	errorFunc EncodeErrorFunc
+	log          *logrus.Logger
+	errorFunc    EncodeErrorFunc
+	responseFunc EncodeResponseFunc
 
-	middlewares []Middleware
+	// mwMu 保护运行时可以修改的中间件, mwVersion在中间件变化时递增, 使缓存的中间件链失效
+	mwMu               sync.RWMutex
+	mwVersion          uint64
+	middlewares        []Middleware
+	groupMiddlewares   []groupMiddleware
+	serviceMiddlewares map[string][]Middleware
+	methodMiddlewares  map[string][]Middleware
+	auths              []Authenticator
+	groupAuths         []groupAuth
+
+	health *health
+
+	metrics       *metrics.Registry
+	metricsPath   string
+	serverMetrics *serverMetrics
+
+	tracer *tracing.Tracer
+
+	enableRecovery bool
+	recoveryOpts   []RecoveryOption
+	recovery       Middleware
 
 	ctx context.Context
 }
@@ -39,19 +65,74 @@ type EncodeErrorFunc func(ctx *gin.Context, err error, log *logrus.Logger)
 
 // DefaultEncodeErrorFunc 默认错误处理函数
 func DefaultEncodeErrorFunc(ctx *gin.Context, err error, log *logrus.Logger) {
-	e, ok := err.(errors.Error)
-	if !ok {
-		e = errors.FromError(errors.UnknownCode, errors.DefaultStatus, errors.UnknownReason, errors.UnknownMessage, err)
-	}
+	// 被fmt.Errorf("%w")等包装的Error同样使用其中的code和reason
+	e := errors.Convert(err)
 
-	ctx.JSON(int(e.HttpStatus()), serialize.Response{
+	if err := writeBody(ctx, int(e.HttpStatus()), serialize.Response{
 		Error: e,
-	})
-	log.Error(e.Error())
+	}); err != nil {
+		log.WithContext(ctx.Request.Context()).Errorf("encode error response failed, %v", err)
+	}
+	log.WithContext(ctx.Request.Context()).Error(e.Error())
 
 	return
 }
 
+// EncodeResponseFunc 响应处理函数, status为响应码
+type EncodeResponseFunc func(ctx *gin.Context, status int, resp interface{}, log *logrus.Logger)
+
+// DefaultEncodeResponseFunc 默认响应处理函数, 根据Accept选择codec直接序列化resp, 默认使用json
+func DefaultEncodeResponseFunc(ctx *gin.Context, status int, resp interface{}, log *logrus.Logger) {
+	if !bodyAllowedForStatus(status) {
+		ctx.Status(status)
+		return
+	}
+
+	if err := writeBody(ctx, status, resp); err != nil {
+		log.Errorf("encode response failed, %v", err)
+		ctx.Status(http.StatusInternalServerError)
+	}
+}
+
+// EnvelopeEncodeResponseFunc 将resp包装为 {"data": resp}, 使成功响应与错误响应的格式保持一致
+// resp先使用协商的codec序列化, proto.Message使用protojson, 与DefaultEncodeResponseFunc的格式相同
+// 只有json支持包装, 协商结果为protobuf, msgpack等其他codec时不包装, 直接返回resp
+func EnvelopeEncodeResponseFunc(ctx *gin.Context, status int, resp interface{}, log *logrus.Logger) {
+	if !bodyAllowedForStatus(status) {
+		ctx.Status(status)
+		return
+	}
+
+	codec := responseCodec(ctx)
+	if codec.ContentType() != json.ContentType {
+		DefaultEncodeResponseFunc(ctx, status, resp, log)
+		return
+	}
+
+	data, err := codec.Marshal(resp)
+	if err == nil {
+		data, err = stdjson.Marshal(serialize.Response{Data: stdjson.RawMessage(data)})
+	}
+	if err != nil {
+		log.Errorf("encode response failed, %v", err)
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	ctx.Data(status, codec.ContentType(), data)
+}
+
+// bodyAllowedForStatus 1xx, 204, 304 不允许携带响应体
+func bodyAllowedForStatus(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == http.StatusNoContent, status == http.StatusNotModified:
+		return false
+	}
+
+	return true
+}
+
 type Option func(s *Server)
 
 func WithAddr(addr string) Option {
@@ -63,18 +144,39 @@ func WithAddr(addr string) Option {
 	}
 }
 
+// WithRouter 使用自定义的gin engine, Server不会修改engine的配置
+// 路径参数中包含转义后的 / 时需要开启UseRawPath, 可以使用WithRawPath
 func WithRouter(router *gin.Engine) Option {
 	return func(s *Server) {
 		s.router = router
 	}
 }
 
+// WithRawPath 开启engine的UseRawPath, 使用原始路径匹配路由, 路径参数中转义后的 / 不会被当作分隔符
+// 默认的engine总是开启, 用于WithRouter传入的engine
+func WithRawPath() Option {
+	return func(s *Server) {
+		s.rawPath = true
+	}
+}
+
 func WithEncodeErrorFunc(fn EncodeErrorFunc) Option {
 	return func(s *Server) {
 		s.errorFunc = fn
 	}
 }
 
+func WithEncodeResponseFunc(fn EncodeResponseFunc) Option {
+	return func(s *Server) {
+		s.responseFunc = fn
+	}
+}
+
+// WithResponseEnvelope 成功响应使用 {"data": resp} 的格式返回, 只对json响应生效, 参考EnvelopeEncodeResponseFunc
+func WithResponseEnvelope() Option {
+	return WithEncodeResponseFunc(EnvelopeEncodeResponseFunc)
+}
+
 func WithLogger(log *logrus.Logger) Option {
 	return func(s *Server) {
 		s.log = log
@@ -87,6 +189,75 @@ func WithContext(ctx context.Context) Option {
 	}
 }
 
+// WithHealthCheck 开启健康检查, 注册 /healthz 和 /readyz 路由
+func WithHealthCheck() Option {
+	return func(s *Server) {
+		if s.health == nil {
+			s.health = newHealth()
+		}
+	}
+}
+
+// WithHealthCheckPath 开启健康检查并设置存活检查和就绪检查的路由
+func WithHealthCheckPath(liveness, readiness string) Option {
+	return func(s *Server) {
+		WithHealthCheck()(s)
+		s.health.livenessPath = liveness
+		s.health.readinessPath = readiness
+	}
+}
+
+// WithHealthCheckTimeout 开启健康检查并设置就绪检查的超时时间, 默认为3s
+func WithHealthCheckTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		WithHealthCheck()(s)
+		s.health.timeout = timeout
+	}
+}
+
+// WithDrainDelay 开启健康检查, Stop时先将就绪检查置为失败, 等待delay后再关闭Server, 使负载均衡有时间摘除流量
+func WithDrainDelay(delay time.Duration) Option {
+	return func(s *Server) {
+		WithHealthCheck()(s)
+		s.health.drainDelay = delay
+	}
+}
+
+// WithMetrics 开启服务端指标, 记录所有请求(包括参数解析失败的请求), 并在 /metrics 路由输出prometheus文本格式的指标
+// 开启后使用相同Registry的Metrics中间件不会重复记录
+func WithMetrics(reg *metrics.Registry) Option {
+	return func(s *Server) {
+		s.metrics = reg
+		if s.metricsPath == "" {
+			s.metricsPath = DefaultMetricsPath
+		}
+	}
+}
+
+// WithMetricsPath 设置输出指标的路由
+func WithMetricsPath(path string) Option {
+	return func(s *Server) {
+		s.metricsPath = path
+	}
+}
+
+// WithTracer 开启链路追踪, 从请求头中解析W3C traceparent并为每个请求创建span
+// 请求失败时trace id会添加到错误的Metadata中
+func WithTracer(tracer *tracing.Tracer) Option {
+	return func(s *Server) {
+		s.tracer = tracer
+	}
+}
+
+// WithRecovery 捕获请求处理过程中(包括参数解析和所有中间件)的panic, 转换为errors.InternalServer,
+// 堆栈通过Server的logger输出. 使用WithRouter传入未添加gin.Recovery的engine时建议开启
+func WithRecovery(opts ...RecoveryOption) Option {
+	return func(s *Server) {
+		s.enableRecovery = true
+		s.recoveryOpts = opts
+	}
+}
+
 func New(opts ...Option) *Server {
 	s := &Server{}
 	for _, opt := range opts {
@@ -95,6 +266,11 @@ func New(opts ...Option) *Server {
 
 	if s.router == nil {
 		s.router = gin.Default()
+		s.rawPath = true
+	}
+	if s.rawPath {
+		// 使用原始路径匹配路由, 路径参数中转义后的 / 不会被当作分隔符, 与EncodeURL生成的路径一致
+		s.router.UseRawPath = true
 	}
 
 	s.server = &http.Server{
@@ -105,10 +281,28 @@ func New(opts ...Option) *Server {
 		s.errorFunc = DefaultEncodeErrorFunc
 	}
 
+	if s.responseFunc == nil {
+		s.responseFunc = DefaultEncodeResponseFunc
+	}
+
 	if s.log == nil {
 		s.log = logrus.StandardLogger()
 	}
 
+	if s.enableRecovery {
+		s.recovery = Recovery(append([]RecoveryOption{WithRecoveryLogger(s.log)}, s.recoveryOpts...)...)
+	}
+
+	if s.health != nil {
+		s.router.GET(s.health.livenessPath, s.health.liveness)
+		s.router.GET(s.health.readinessPath, s.health.readiness)
+	}
+
+	if s.metrics != nil {
+		s.router.GET(s.metricsPath, metricsHandler(s.metrics))
+		s.serverMetrics = newServerMetrics(s.metrics)
+	}
+
 	if s.ctx == nil {
 		s.ctx = context.Background()
 	}
@@ -133,7 +327,7 @@ func (s *Server) GinEngine() *gin.Engine {
 	return s.router
 }
 
-func (s *Server) RegisterService(sd *ServiceDesc, srv interface{}) {
+func (s *Server) RegisterService(sd *ServiceDesc, srv interface{}, opts ...RegisterOption) {
 	if srv != nil {
 		ht := reflect.TypeOf(sd.HandlerType).Elem()
 		st := reflect.TypeOf(srv)
@@ -142,18 +336,90 @@ func (s *Server) RegisterService(sd *ServiceDesc, srv interface{}) {
 		}
 	}
 
-	s.register(sd, srv)
+	o := &registerOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	s.register(sd, srv, o)
 }
 
-func (s *Server) register(sd *ServiceDesc, srv interface{}) {
-	for _, d := range sd.Methods {
-		handler := d.Handler
-		s.handle(d.Method, d.Path, func(ctx context.Context, req interface{}) (resp interface{}, err error) {
-			return handler(srv, ctx, reqDecoder(ctx), chainHandler(s.middlewares))
+func (s *Server) register(sd *ServiceDesc, srv interface{}, o *registerOptions) {
+	for i := range sd.Methods {
+		md := &sd.Methods[i]
+		cache := &chainCache{}
+		s.handle(sd, md, o, func(ctx context.Context, req interface{}) (resp interface{}, err error) {
+			e := s.cachedMiddlewareChain(cache, sd, md, o)
+			// 认证在解析请求参数之前执行
+			if ctx, err = authenticate(ctx, e.auths); err != nil {
+				return nil, err
+			}
+			return md.Handler(srv, ctx, reqDecoder(ctx), e.chain)
 		})
 	}
 }
 
+// chainCache 缓存方法的中间件链和认证函数, 中间件变化后在下一次请求时重新构建
+type chainCache struct {
+	entry atomic.Pointer[chainEntry]
+}
+
+type chainEntry struct {
+	version uint64
+	chain   Middleware
+	auths   []Authenticator
+}
+
+func (s *Server) cachedMiddlewareChain(cache *chainCache, sd *ServiceDesc, md *MethodDesc, o *registerOptions) *chainEntry {
+	s.mwMu.RLock()
+	defer s.mwMu.RUnlock()
+
+	if e := cache.entry.Load(); e != nil && e.version == s.mwVersion {
+		return e
+	}
+	e := &chainEntry{
+		version: s.mwVersion,
+		chain:   chainHandler(s.methodMiddlewareChain(sd, md, o)),
+		auths:   s.methodAuths(md),
+	}
+	cache.entry.Store(e)
+
+	return e
+}
+
+// methodAuths 按照 全局 -> 路由组 的顺序获取方法的认证函数, 调用时需要持有mwMu
+func (s *Server) methodAuths(md *MethodDesc) []Authenticator {
+	auths := append([]Authenticator(nil), s.auths...)
+	for _, g := range s.groupAuths {
+		if hasPathPrefix(md.Path, g.prefix) {
+			auths = append(auths, g.auths...)
+		}
+	}
+
+	return auths
+}
+
+// methodMiddlewareChain 按照 全局 -> 路由组 -> service -> method 的顺序获取方法的中间件, 调用时需要持有mwMu
+func (s *Server) methodMiddlewareChain(sd *ServiceDesc, md *MethodDesc, o *registerOptions) []Middleware {
+	middlewares := make([]Middleware, 0, len(s.middlewares))
+	middlewares = append(middlewares, s.middlewares...)
+	for _, g := range s.groupMiddlewares {
+		if hasPathPrefix(md.Path, g.prefix) {
+			middlewares = append(middlewares, g.middlewares...)
+		}
+	}
+	if sd.ServiceName != "" {
+		middlewares = append(middlewares, s.serviceMiddlewares[sd.ServiceName]...)
+	}
+	middlewares = append(middlewares, o.middlewares...)
+	middlewares = append(middlewares, s.methodMiddlewares[methodKey(md.Method, md.Path)]...)
+	if md.Name != "" {
+		middlewares = append(middlewares, o.methodMiddlewares[md.Name]...)
+	}
+
+	return middlewares
+}
+
 func chainHandler(middlewares []Middleware) Middleware {
 	if len(middlewares) == 0 {
 		return nil
@@ -174,26 +440,139 @@ func getChainMiddleware(middlewares []Middleware, cur int, handler Handler) Hand
 	}
 }
 
-func (s *Server) handle(method, relativePath string, handler Handler) {
-	s.router.Handle(method, relativePath, s.handlerConvert(handler))
+func (s *Server) handle(sd *ServiceDesc, md *MethodDesc, o *registerOptions, handler Handler) {
+	if recovery := s.recovery; recovery != nil {
+		next := handler
+		handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+			return recovery(ctx, req, next)
+		}
+	}
+	s.router.Handle(md.Method, md.Path, s.handlerConvert(sd, md, o.statusCode(md.Name), handler))
 }
 
-func (s *Server) handlerConvert(handler Handler) gin.HandlerFunc {
+func (s *Server) handlerConvert(sd *ServiceDesc, md *MethodDesc, status int, handler Handler) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		tr := &Transport{
+			operation:    md.FullMethod,
+			serviceName:  sd.ServiceName,
+			method:       md.Method,
+			pathTemplate: md.Path,
+			reqHeader:    c.Request.Header,
+			replyHeader:  c.Writer.Header(),
+			statusCode:   status,
+		}
+		var (
+			resp     interface{}
+			err      error
+			finished bool
+		)
+		if s.serverMetrics != nil {
+			tr.metrics = s.metrics
+			done := s.serverMetrics.observe(md.FullMethod, md.Method, md.Path)
+			defer func() {
+				if !finished {
+					done(errors.DefaultStatus, observePanic)
+					return
+				}
+				done(tr.statusCode, err)
+			}()
+		}
+
 		ctx := context.WithValue(s.ctx, "gin-ctx", c)
-		resp, err := handler(ctx, nil)
+		ctx = NewServerContext(ctx, tr)
+		// 客户端通过请求头传递了超时时间时, 为handler的ctx设置相应的deadline
+		if timeout, ok := parseTimeoutHeader(c.Request.Header); ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		if s.tracer != nil {
+			var span *tracing.Span
+			ctx, span = startServerSpan(ctx, s.tracer, tr)
+			// 错误处理函数使用请求的context打印日志, 使tracing.LogrusHook能够添加trace id
+			c.Request = c.Request.WithContext(tracing.ContextWithSpan(c.Request.Context(), span))
+			setTraceHeader(tr.ReplyHeader(), span)
+			defer func() {
+				endSpan(span, c.Writer.Status(), nil)
+			}()
+		}
+		resp, err = handler(ctx, nil)
+		finished = true
+		if err != nil && s.tracer != nil {
+			span := tracing.SpanFromContext(ctx)
+			span.RecordError(err)
+			err = withTraceID(err, span.SpanContext().TraceID.String())
+		}
 		if err != nil && s.errorFunc != nil {
 			s.errorFunc(c, err, s.log)
 			return
 		}
-		c.JSON(http.StatusOK, resp)
+		s.responseFunc(c, tr.statusCode, resp, s.log)
 	}
 }
 
+// Middleware 添加全局中间件, 作用于所有service的所有方法
 func (s *Server) Middleware(middleware ...Middleware) {
+	s.mwMu.Lock()
+	defer s.mwMu.Unlock()
+	s.mwVersion++
 	s.middlewares = append(s.middlewares, middleware...)
 }
 
+// Auth 添加全局认证, 例如 Auth(JWT(keyFunc)), 认证在解析请求参数和执行中间件之前进行
+// 可以使用WithAuthSkip跳过不需要认证的接口
+func (s *Server) Auth(auth ...Authenticator) {
+	s.mwMu.Lock()
+	defer s.mwMu.Unlock()
+	s.mwVersion++
+	s.auths = append(s.auths, auth...)
+}
+
+// GroupAuth 为路径前缀为prefix的路由组添加认证, 例如 /admin
+func (s *Server) GroupAuth(prefix string, auth ...Authenticator) {
+	s.mwMu.Lock()
+	defer s.mwMu.Unlock()
+	s.mwVersion++
+	s.groupAuths = append(s.groupAuths, groupAuth{
+		prefix: prefix,
+		auths:  auth,
+	})
+}
+
+// GroupMiddleware 为路径前缀为prefix的路由组添加中间件, 例如 /admin
+func (s *Server) GroupMiddleware(prefix string, middleware ...Middleware) {
+	s.mwMu.Lock()
+	defer s.mwMu.Unlock()
+	s.mwVersion++
+	s.groupMiddlewares = append(s.groupMiddlewares, groupMiddleware{
+		prefix:      prefix,
+		middlewares: middleware,
+	})
+}
+
+// ServiceMiddleware 为名称为service的服务添加中间件, service为ServiceDesc中的ServiceName
+func (s *Server) ServiceMiddleware(service string, middleware ...Middleware) {
+	s.mwMu.Lock()
+	defer s.mwMu.Unlock()
+	s.mwVersion++
+	if s.serviceMiddlewares == nil {
+		s.serviceMiddlewares = make(map[string][]Middleware)
+	}
+	s.serviceMiddlewares[service] = append(s.serviceMiddlewares[service], middleware...)
+}
+
+// MethodMiddleware 为请求方法为method, 路由为path的方法添加中间件, 例如 MethodMiddleware("GET", "/user/:id", ...)
+func (s *Server) MethodMiddleware(method, path string, middleware ...Middleware) {
+	s.mwMu.Lock()
+	defer s.mwMu.Unlock()
+	s.mwVersion++
+	if s.methodMiddlewares == nil {
+		s.methodMiddlewares = make(map[string][]Middleware)
+	}
+	key := methodKey(method, path)
+	s.methodMiddlewares[key] = append(s.methodMiddlewares[key], middleware...)
+}
+
 func (s *Server) Start() error {
 	s.log.Info("server listen at ", s.addr)
 	err := s.server.ListenAndServe()
@@ -204,84 +583,38 @@ func (s *Server) Start() error {
 	return err
 }
 
+// RegisterHealthChecker 注册名称为name的就绪检查, 需要使用WithHealthCheck开启健康检查
+func (s *Server) RegisterHealthChecker(name string, checker HealthChecker) {
+	if s.health == nil {
+		s.log.Warnf("health check is disabled, checker %s ignored", name)
+		return
+	}
+	s.health.register(name, checker)
+}
+
 func (s *Server) Stop(ctx context.Context) error {
+	if s.health != nil {
+		s.health.drain(ctx)
+	}
 	return s.server.Shutdown(ctx)
 }
 
+// BindVar 解析请求参数, 请求体的Content-Type为已注册的codec时使用codec解析,
+// GET请求使用form tag解析query参数, 编码规则与EncodeURL一致, 否则使用gin进行绑定
+// GET请求的解析与gin的form绑定兼容: 没有form tag的字段使用字段名, 支持default, time_format, time_utc以及time_location,
+// 另外支持 a.b 形式的嵌套message字段, proto枚举名以及google.protobuf包装类型
 func BindVar(ctx context.Context, val interface{}) error {
 	c := ctx.Value("gin-ctx").(*gin.Context)
 	if err := bindParam(c, val); err != nil {
 		return err
 	}
-	return c.ShouldBind(val)
-}
-
-// 解析路径/xxx/:yyy 中的参数
-func bindParam(ctx *gin.Context, val interface{}) error {
-	if len(ctx.Params) == 0 || !strings.Contains(ctx.FullPath(), ":") {
-		return nil
-	}
-
-	params := ctx.Params
-	v := reflect.ValueOf(val)
-	if v.Type().Kind() == reflect.Ptr {
-		v = v.Elem()
-	}
-	t := v.Type()
-
-	if v.Type().Kind() != reflect.Struct {
-		return stderr.New("must be struct")
+	if codec, ok := requestCodec(c); ok {
+		return decodeBody(c, codec, val)
 	}
-
-	n := v.NumField()
-	for i := 0; i < n; i++ {
-		fieldInfo := t.Field(i)
-		fieldVal := v.Field(i)
-		key := fieldInfo.Tag.Get(ParamKey)
-		if key == "" {
-			continue
-		}
-		val := params.ByName(key)
-		if val == "" {
-			continue
-		}
-
-		fieldType := fieldInfo.Type
-		if fieldType.Kind() == reflect.Pointer {
-			fieldType = fieldType.Elem()
-			if fieldVal.IsNil() {
-				fieldVal.Set(reflect.New(fieldType))
-			}
-			fieldVal = fieldVal.Elem()
-		}
-
-		switch fieldType.Kind() {
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
-			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			n, err := strconv.ParseInt(val, 10, 64)
-			if err != nil {
-				return err
-			}
-			fieldVal.SetInt(n)
-		case reflect.Float32, reflect.Float64:
-			n, err := strconv.ParseFloat(val, 64)
-			if err != nil {
-				return err
-			}
-			fieldVal.SetFloat(n)
-		case reflect.String:
-			fieldVal.SetString(val)
-		case reflect.Bool:
-			b, err := strconv.ParseBool(val)
-			if err != nil {
-				return err
-			}
-			fieldVal.SetBool(b)
-		default:
-		}
+	if c.Request.Method == http.MethodGet {
+		return bindQuery(c.Request.URL.Query(), val)
 	}
-
-	return nil
+	return c.ShouldBind(val)
 }
 
 func reqDecoder(ctx context.Context) func(interface{}) error {
@@ -0,0 +1,1292 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	stderr "errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mangohow/mangokit/encoding"
+	protoenc "github.com/mangohow/mangokit/encoding/proto"
+	"github.com/mangohow/mangokit/errors"
+	"github.com/mangohow/mangokit/metrics"
+	"github.com/mangohow/mangokit/tracing"
+	"github.com/mangohow/mangokit/transport/balancer"
+	"github.com/mangohow/mangokit/transport/breaker"
+	"github.com/mangohow/mangokit/transport/resolver"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+type testService interface {
+	Call(ctx context.Context) error
+}
+
+type testServiceImpl struct{}
+
+func (testServiceImpl) Call(ctx context.Context) error { return nil }
+
+func testMethodHandler(svc interface{}, ctx context.Context, dec func(interface{}) error, middleware Middleware) (interface{}, error) {
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, svc.(testService).Call(ctx)
+	}
+	if middleware == nil {
+		return handler(ctx, nil)
+	}
+
+	return middleware(ctx, nil, handler)
+}
+
+func newTestServiceDesc() *ServiceDesc {
+	return &ServiceDesc{
+		ServiceName: "Test",
+		HandlerType: (*testService)(nil),
+		Methods: []MethodDesc{
+			{Name: "GetUser", FullMethod: "/test.Test/GetUser", Method: "GET", Path: "/admin/user", Handler: testMethodHandler},
+			{Name: "Public", FullMethod: "/test.Test/Public", Method: "GET", Path: "/administrator", Handler: testMethodHandler},
+		},
+	}
+}
+
+func serve(s *Server, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(method, path, nil)
+	s.GinEngine().ServeHTTP(w, r)
+
+	return w
+}
+
+func TestMiddlewareScope(t *testing.T) {
+	var trace []string
+	mark := func(name string) Middleware {
+		return func(ctx context.Context, req interface{}, next Handler) (interface{}, error) {
+			trace = append(trace, name)
+			return next(ctx, req)
+		}
+	}
+
+	s := New(WithRouter(gin.New()))
+	s.Middleware(mark("global"))
+	s.GroupMiddleware("/admin", mark("group"))
+	s.ServiceMiddleware("Test", mark("service"))
+	s.MethodMiddleware("GET", "/admin/user", mark("method"))
+	s.RegisterService(newTestServiceDesc(), testServiceImpl{},
+		WithServiceMiddleware(mark("register-service")),
+		WithMethodMiddleware("GetUser", mark("register-method")))
+
+	tests := []struct {
+		path   string
+		expect []string
+	}{
+		{"/admin/user", []string{"global", "group", "service", "register-service", "method", "register-method"}},
+		{"/administrator", []string{"global", "service", "register-service"}},
+	}
+	for _, tt := range tests {
+		trace = nil
+		w := serve(s, "GET", tt.path)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: unexpected status %d", tt.path, w.Code)
+		}
+		if !reflect.DeepEqual(trace, tt.expect) {
+			t.Errorf("%s: middleware order = %v, want %v", tt.path, trace, tt.expect)
+		}
+	}
+}
+
+func TestMiddlewareAddedAtRuntime(t *testing.T) {
+	var count int32
+	s := New(WithRouter(gin.New()))
+	s.RegisterService(newTestServiceDesc(), testServiceImpl{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				serve(s, "GET", "/admin/user")
+			}
+		}()
+	}
+	s.Middleware(func(ctx context.Context, req interface{}, next Handler) (interface{}, error) {
+		atomic.AddInt32(&count, 1)
+		return next(ctx, req)
+	})
+	s.ServiceMiddleware("Test", func(ctx context.Context, req interface{}, next Handler) (interface{}, error) {
+		return next(ctx, req)
+	})
+	wg.Wait()
+
+	before := atomic.LoadInt32(&count)
+	serve(s, "GET", "/admin/user")
+	if atomic.LoadInt32(&count) != before+1 {
+		t.Error("middleware added after registration is not applied")
+	}
+}
+
+func TestServerTransport(t *testing.T) {
+	var tr *Transport
+	s := New(WithRouter(gin.New()))
+	s.Middleware(func(ctx context.Context, req interface{}, next Handler) (interface{}, error) {
+		tr, _ = FromServerContext(ctx)
+		tr.ReplyHeader().Set("X-Operation", tr.Operation())
+		return next(ctx, req)
+	})
+	s.RegisterService(newTestServiceDesc(), testServiceImpl{})
+
+	w := serve(s, "GET", "/admin/user")
+	if tr == nil {
+		t.Fatal("transport not found in context")
+	}
+	if tr.Operation() != "/test.Test/GetUser" || tr.ServiceName() != "Test" ||
+		tr.Method() != "GET" || tr.PathTemplate() != "/admin/user" {
+		t.Errorf("unexpected transport %+v", tr)
+	}
+	if w.Header().Get("X-Operation") != "/test.Test/GetUser" {
+		t.Errorf("reply header not written, got %q", w.Header().Get("X-Operation"))
+	}
+}
+
+type testReply struct {
+	Message string `json:"message"`
+}
+
+type replyService interface {
+	Create(ctx context.Context) (*testReply, error)
+	Delete(ctx context.Context) error
+}
+
+type replyServiceImpl struct{}
+
+func (replyServiceImpl) Create(ctx context.Context) (*testReply, error) {
+	return &testReply{Message: "created"}, nil
+}
+
+func (replyServiceImpl) Delete(ctx context.Context) error { return nil }
+
+func newReplyServiceDesc() *ServiceDesc {
+	return &ServiceDesc{
+		ServiceName: "Reply",
+		HandlerType: (*replyService)(nil),
+		Methods: []MethodDesc{
+			{Name: "Create", Method: "POST", Path: "/reply", Handler: func(svc interface{}, ctx context.Context, dec func(interface{}) error, middleware Middleware) (interface{}, error) {
+				return svc.(replyService).Create(ctx)
+			}},
+			{Name: "Delete", Method: "DELETE", Path: "/reply", Handler: func(svc interface{}, ctx context.Context, dec func(interface{}) error, middleware Middleware) (interface{}, error) {
+				return nil, svc.(replyService).Delete(ctx)
+			}},
+		},
+	}
+}
+
+func TestResponseEnvelope(t *testing.T) {
+	s := New(WithRouter(gin.New()), WithResponseEnvelope())
+	s.RegisterService(newReplyServiceDesc(), replyServiceImpl{},
+		WithMethodStatus("Create", http.StatusCreated),
+		WithMethodStatus("Delete", http.StatusNoContent))
+
+	w := serve(s, "POST", "/reply")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("unexpected status %d", w.Code)
+	}
+	if body := w.Body.String(); body != `{"data":{"message":"created"},"error":null}` {
+		t.Errorf("unexpected body %s", body)
+	}
+
+	w = serve(s, "DELETE", "/reply")
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Errorf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+
+	ts := httptest.NewServer(s.GinEngine())
+	defer ts.Close()
+	cli, err := NewClient(WithEndpoint(ts.URL), WithDecodeEnvelope())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply := new(testReply)
+	status, err := cli.Invoke(context.Background(), "POST", "/reply", nil, reply)
+	if err != nil || status != http.StatusCreated || reply.Message != "created" {
+		t.Errorf("invoke: status=%d reply=%+v err=%v", status, reply, err)
+	}
+
+	// proto.Message的data使用protojson序列化, protobuf响应不包装
+	for _, tt := range []struct {
+		accept string
+		body   string
+	}{
+		{accept: "application/json", body: `{"data":"42","error":null}`},
+		{accept: protoenc.ContentType, body: string(mustMarshal(t, protoenc.ContentType, wrapperspb.Int64(42)))},
+	} {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest("GET", "/", nil)
+		ctx.Request.Header.Set("Accept", tt.accept)
+		EnvelopeEncodeResponseFunc(ctx, http.StatusOK, wrapperspb.Int64(42), logrus.StandardLogger())
+		if w.Body.String() != tt.body || !strings.HasPrefix(w.Header().Get("Content-Type"), tt.accept) {
+			t.Errorf("%s: unexpected response %q %s", tt.accept, w.Body.String(), w.Header().Get("Content-Type"))
+		}
+
+		v := new(wrapperspb.Int64Value)
+		if err := cli.decodeResponse(encoding.GetCodec(tt.accept), w.Body.Bytes(), v); err != nil || v.Value != 42 {
+			t.Errorf("%s: decode %v %v", tt.accept, v, err)
+		}
+	}
+}
+
+func mustMarshal(t *testing.T, contentType string, v interface{}) []byte {
+	t.Helper()
+	data, err := encoding.GetCodec(contentType).Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+type echoService interface {
+	Echo(ctx context.Context, req *testReply) (*testReply, error)
+}
+
+type echoServiceImpl struct{}
+
+func (echoServiceImpl) Echo(ctx context.Context, req *testReply) (*testReply, error) {
+	switch req.Message {
+	case "fail":
+		return nil, errors.Clone(errors.BadRequest(1, "ECHO_FAILED", "echo failed")).WithMetadata("field", "message")
+	case "wrapped":
+		return nil, fmt.Errorf("echo: %w", errors.BadRequest(1, "ECHO_FAILED", "echo failed"))
+	case "panic":
+		panic("echo panic")
+	}
+	return &testReply{Message: "echo " + req.Message}, nil
+}
+
+func newEchoServiceDesc() *ServiceDesc {
+	return &ServiceDesc{
+		ServiceName: "Echo",
+		HandlerType: (*echoService)(nil),
+		Methods: []MethodDesc{
+			{Name: "Echo", FullMethod: "/test.Echo/Echo", Method: "POST", Path: "/echo", Handler: func(svc interface{}, ctx context.Context, dec func(interface{}) error, middleware Middleware) (interface{}, error) {
+				in := new(testReply)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+					return svc.(echoService).Echo(ctx, in)
+				}
+				if middleware == nil {
+					return handler(ctx, in)
+				}
+				return middleware(ctx, in, handler)
+			}},
+		},
+	}
+}
+
+func TestContentNegotiation(t *testing.T) {
+	s := New(WithRouter(gin.New()))
+	s.RegisterService(newEchoServiceDesc(), echoServiceImpl{})
+	ts := httptest.NewServer(s.GinEngine())
+	defer ts.Close()
+
+	for _, ct := range []string{"application/json", "application/x-msgpack"} {
+		var contentType string
+		cli, err := NewClient(WithEndpoint(ts.URL), WithContentType(ct), WithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			resp, err := http.DefaultTransport.RoundTrip(r)
+			if err == nil {
+				contentType = resp.Header.Get("Content-Type")
+			}
+			return resp, err
+		})))
+		if err != nil {
+			t.Fatal(err)
+		}
+		reply := new(testReply)
+		if _, err = cli.Invoke(context.Background(), "POST", "/echo", &testReply{Message: "mango"}, reply); err != nil {
+			t.Fatalf("%s: invoke error %v", ct, err)
+		}
+		if reply.Message != "echo mango" || contentType != ct {
+			t.Errorf("%s: got reply %+v with content type %s", ct, reply, contentType)
+		}
+	}
+}
+
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+type validateFieldError struct {
+	field  string
+	reason string
+	cause  error
+}
+
+func (e validateFieldError) Error() string  { return e.field + ": " + e.reason }
+func (e validateFieldError) Field() string  { return e.field }
+func (e validateFieldError) Reason() string { return e.reason }
+func (e validateFieldError) Cause() error   { return e.cause }
+
+type validateMultiError []error
+
+func (m validateMultiError) Error() string      { return "validate failed" }
+func (m validateMultiError) AllErrors() []error { return m }
+
+type validateRequest struct{}
+
+func (validateRequest) ValidateAll() error {
+	return validateMultiError{
+		validateFieldError{field: "Name", reason: "value length must be at least 1 runes"},
+		validateFieldError{field: "Address", reason: "embedded message failed validation",
+			cause: validateFieldError{field: "City", reason: "value is required"}},
+	}
+}
+
+func TestValidator(t *testing.T) {
+	called := false
+	_, err := Validator()(context.Background(), validateRequest{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		called = true
+		return nil, nil
+	})
+	if called {
+		t.Fatal("handler should not be called")
+	}
+	e, ok := err.(errors.Error)
+	if !ok || e.HttpStatus() != http.StatusBadRequest || e.Reason() != ValidationReason {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expect := map[string]string{
+		"Name":         "value length must be at least 1 runes",
+		"Address.City": "value is required",
+	}
+	if !reflect.DeepEqual(e.Metadata(), expect) {
+		t.Errorf("metadata = %v, want %v", e.Metadata(), expect)
+	}
+
+	if _, err = Validator()(context.Background(), &testReply{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	}); err != nil {
+		t.Errorf("request without Validate should pass, got %v", err)
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	s := New(WithRouter(gin.New()), WithHealthCheck())
+	dbErr := stderr.New("connection refused")
+	var dbDown bool
+	s.RegisterHealthChecker("cache", func(ctx context.Context) error { return nil })
+	s.RegisterHealthChecker("db", func(ctx context.Context) error {
+		if dbDown {
+			return dbErr
+		}
+		return nil
+	})
+
+	if w := serve(s, "GET", DefaultLivenessPath); w.Code != http.StatusOK {
+		t.Fatalf("liveness: unexpected status %d", w.Code)
+	}
+
+	w := serve(s, "GET", DefaultReadinessPath)
+	var resp HealthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || resp.Status != HealthStatusUp || len(resp.Checks) != 2 {
+		t.Errorf("readiness: unexpected response %d %s", w.Code, w.Body.String())
+	}
+
+	dbDown = true
+	w = serve(s, "GET", DefaultReadinessPath)
+	resp = HealthResponse{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusServiceUnavailable || resp.Checks["db"].Error != dbErr.Error() ||
+		resp.Checks["cache"].Status != HealthStatusUp {
+		t.Errorf("readiness: unexpected response %d %s", w.Code, w.Body.String())
+	}
+
+	dbDown = false
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if w = serve(s, "GET", DefaultReadinessPath); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("readiness after stop: unexpected status %d", w.Code)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	s := New(WithRouter(gin.New()), WithMetrics(reg))
+	// 与WithMetrics使用相同的Registry, 请求不会被重复记录
+	s.Middleware(Metrics(reg))
+	s.RegisterService(newEchoServiceDesc(), echoServiceImpl{})
+	ts := httptest.NewServer(s.GinEngine())
+	defer ts.Close()
+
+	cli, err := NewClient(WithEndpoint(ts.URL), WithClientMetrics(reg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply := new(testReply)
+	if _, err = cli.Invoke(context.Background(), "POST", "/echo", &testReply{Message: "mango"}, reply,
+		OperationCallOption("/test.Echo/Echo"), PathTemplateCallOption("/echo")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 参数解析失败的请求同样需要记录
+	r := httptest.NewRequest("POST", "/echo", strings.NewReader("{"))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	s.GinEngine().ServeHTTP(w, r)
+	if w.Code == http.StatusOK {
+		t.Fatal("expect decode error")
+	}
+
+	body := serve(s, "GET", DefaultMetricsPath).Body.String()
+	for _, line := range []string{
+		`mangokit_http_server_requests_total{operation="/test.Echo/Echo",method="POST",route="/echo",code="-1",status="500"} 1`,
+		`mangokit_http_server_requests_total{operation="/test.Echo/Echo",method="POST",route="/echo",code="0",status="200"} 1`,
+		`mangokit_http_server_requests_in_flight{operation="/test.Echo/Echo",method="POST",route="/echo"} 0`,
+		`mangokit_http_server_request_duration_seconds_count{operation="/test.Echo/Echo",method="POST",route="/echo"} 2`,
+		`mangokit_http_client_requests_total{operation="/test.Echo/Echo",method="POST",route="/echo",code="0",status="200"} 1`,
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("metrics output missing %s\n%s", line, body)
+		}
+	}
+}
+
+func TestMetricsPanic(t *testing.T) {
+	reg := metrics.NewRegistry()
+	m := Metrics(reg)
+	func() {
+		defer func() { recover() }()
+		m(context.Background(), nil, func(ctx context.Context, req interface{}) (interface{}, error) {
+			panic("boom")
+		})
+	}()
+
+	var buf bytes.Buffer
+	if _, err := reg.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`mangokit_http_server_requests_in_flight{operation="",method="",route=""} 0`,
+		`mangokit_http_server_requests_total{operation="",method="",route="",code="-1",status="500"} 1`,
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("metrics output missing %s\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestTracing(t *testing.T) {
+	exporter := tracing.NewInMemoryExporter()
+	tracer := tracing.NewTracer(tracing.WithExporter(exporter))
+	s := New(WithRouter(gin.New()), WithTracer(tracer))
+	s.RegisterService(newEchoServiceDesc(), echoServiceImpl{})
+	ts := httptest.NewServer(s.GinEngine())
+	defer ts.Close()
+
+	cli, err := NewClient(WithEndpoint(ts.URL), WithClientTracer(tracer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cli.Invoke(context.Background(), "POST", "/echo", &testReply{Message: "mango"}, new(testReply),
+		OperationCallOption("/test.Echo/Echo")); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("expect 2 spans, got %d", len(spans))
+	}
+	server, client := spans[0], spans[1]
+	if server.Kind != tracing.SpanKindServer || client.Kind != tracing.SpanKindClient {
+		t.Fatalf("unexpected span kinds %v %v", server.Kind, client.Kind)
+	}
+	if server.SpanContext.TraceID != client.SpanContext.TraceID || server.ParentSpanID != client.SpanContext.SpanID {
+		t.Error("server span should be a child of client span")
+	}
+	if server.Name != "/test.Echo/Echo" || server.Attributes["http.status_code"] != "200" {
+		t.Errorf("unexpected server span %+v", server)
+	}
+
+	exporter.Reset()
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/echo", strings.NewReader(`{"message":"fail"}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set(tracing.TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	s.GinEngine().ServeHTTP(w, r)
+
+	if !strings.Contains(w.Body.String(), `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`) {
+		t.Errorf("trace id not found in error metadata: %s", w.Body.String())
+	}
+	if w.Header().Get("X-Trace-Id") != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("unexpected trace header %q", w.Header().Get("X-Trace-Id"))
+	}
+	spans = exporter.Spans()
+	if len(spans) != 1 || spans[0].Err == nil || spans[0].Attributes["http.status_code"] != "400" {
+		t.Errorf("unexpected spans %+v", spans)
+	}
+
+	// 被包装的错误同样需要添加trace id, 服务端的错误日志中包含trace id
+	hook := &entryHook{}
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	log.AddHook(tracing.LogrusHook{})
+	log.AddHook(hook)
+	s = New(WithRouter(gin.New()), WithTracer(tracer), WithLogger(log))
+	s.RegisterService(newEchoServiceDesc(), echoServiceImpl{})
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("POST", "/echo", strings.NewReader(`{"message":"wrapped"}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set(tracing.TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	s.GinEngine().ServeHTTP(w, r)
+
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`) {
+		t.Errorf("trace id not found in wrapped error: %d %s", w.Code, w.Body.String())
+	}
+	if len(hook.entries) != 1 || hook.entries[0].Data["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace id not found in error log: %+v", hook.entries)
+	}
+}
+
+type entryHook struct {
+	entries []*logrus.Entry
+}
+
+func (h *entryHook) Levels() []logrus.Level { return logrus.AllLevels }
+
+func (h *entryHook) Fire(entry *logrus.Entry) error {
+	h.entries = append(h.entries, entry)
+	return nil
+}
+
+func TestRecovery(t *testing.T) {
+	var buf bytes.Buffer
+	log := logrus.New()
+	log.SetOutput(&buf)
+	s := New(WithRouter(gin.New()), WithLogger(log), WithRecovery(WithRecoveryReason("ECHO_PANIC")))
+	s.RegisterService(newEchoServiceDesc(), echoServiceImpl{})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/echo", strings.NewReader(`{"message":"panic"}`))
+	r.Header.Set("Content-Type", "application/json")
+	s.GinEngine().ServeHTTP(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("unexpected status %d", w.Code)
+	}
+	if body := w.Body.String(); !strings.Contains(body, `"reason":"ECHO_PANIC"`) || strings.Contains(body, "echo panic") {
+		t.Errorf("unexpected body %s", body)
+	}
+	if out := buf.String(); !strings.Contains(out, "echo panic") || !strings.Contains(out, "recovery.go") {
+		t.Errorf("panic stack not logged: %s", out)
+	}
+
+	_, err := Recovery(WithRecoveryHandler(func(ctx context.Context, req, p interface{}) error {
+		return errors.ServiceUnavailable(503, "CUSTOM", fmt.Sprint(p))
+	}), WithRecoveryLogger(log))(context.Background(), nil, func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("boom")
+	})
+	if e, ok := err.(errors.Error); !ok || e.Reason() != "CUSTOM" || e.Message() != "boom" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestInvokeTimeout(t *testing.T) {
+	var remaining time.Duration
+	s := New(WithRouter(gin.New()))
+	s.Middleware(func(ctx context.Context, req interface{}, next Handler) (interface{}, error) {
+		if deadline, ok := ctx.Deadline(); ok {
+			remaining = time.Until(deadline)
+		}
+		return next(ctx, req)
+	})
+	s.RegisterService(newEchoServiceDesc(), echoServiceImpl{})
+	ts := httptest.NewServer(s.GinEngine())
+	defer ts.Close()
+
+	cli, err := NewClient(WithEndpoint(ts.URL), WithTimeout(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cli.Invoke(context.Background(), "POST", "/echo", &testReply{Message: "mango"}, new(testReply),
+		TimeoutCallOption(5*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if remaining <= 4*time.Second || remaining > 5*time.Second {
+		t.Errorf("unexpected server deadline %v", remaining)
+	}
+
+	block := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-block:
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+	defer close(block)
+
+	cli, _ = NewClient(WithEndpoint(slow.URL), WithTimeout(50*time.Millisecond))
+	start := time.Now()
+	_, err = cli.Invoke(context.Background(), "GET", "/", nil, nil)
+	if !stderr.Is(err, context.DeadlineExceeded) || time.Since(start) > time.Second {
+		t.Errorf("expect deadline exceeded, got %v after %v", err, time.Since(start))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = cli.Invoke(ctx, "GET", "/", nil, nil); !stderr.Is(err, context.Canceled) {
+		t.Errorf("expect canceled, got %v", err)
+	}
+}
+
+type retriesCallOption struct {
+	EmptyCallOptions
+	retries *int
+}
+
+func (c retriesCallOption) After(info *AfterCallInfo) {
+	*c.retries = info.Retries
+}
+
+type afterCallOption struct {
+	EmptyCallOptions
+	info *AfterCallInfo
+}
+
+func (c afterCallOption) After(info *AfterCallInfo) {
+	*c.info = *info
+}
+
+func TestInvokeAfter(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer ts.Close()
+
+	exporter := tracing.NewInMemoryExporter()
+	cli, err := NewClient(WithResolver(resolver.NewStatic(ts.URL)), WithClientTracer(tracing.NewTracer(tracing.WithExporter(exporter))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	// 3xx不视为成功, 请求失败时同样执行After
+	var info AfterCallInfo
+	status, err := cli.Invoke(context.Background(), "GET", "/books", nil, new(testReply), afterCallOption{info: &info})
+	if err == nil || status != http.StatusNotModified || info.Status != status || info.Err != err {
+		t.Errorf("unexpected result: status=%d err=%v after=%+v", status, err, info)
+	}
+	// http.url使用负载均衡选择的实例地址
+	if spans := exporter.Spans(); len(spans) != 1 || spans[0].Attributes["http.url"] != ts.URL+"/books" {
+		t.Errorf("unexpected spans %+v", spans)
+	}
+
+	cli, _ = NewClient(WithResolver(resolver.NewStatic()))
+	defer cli.Close()
+	info = AfterCallInfo{Status: -1}
+	if _, err = cli.Invoke(context.Background(), "GET", "/", nil, nil, afterCallOption{info: &info}); err == nil || info.Err != err || info.Status != 0 {
+		t.Errorf("unexpected result: err=%v after=%+v", err, info)
+	}
+}
+
+func TestInvokeRetry(t *testing.T) {
+	var (
+		attempts int
+		bodies   []string
+		fail     int
+		reason   string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if attempts <= fail {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintf(w, `{"data":null,"error":{"code":1,"reason":%q,"message":"busy"}}`, reason)
+			return
+		}
+		w.Write([]byte(`{"message":"ok"}`))
+	}))
+	defer ts.Close()
+
+	policy := &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, RetryableStatus: []int{http.StatusServiceUnavailable}}
+	cli, err := NewClient(WithEndpoint(ts.URL), WithRetry(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		fail     int
+		reason   string
+		opts     []CallOption
+		attempts int
+		status   int
+	}{
+		{name: "retry until success", method: "PUT", fail: 2, attempts: 3, status: http.StatusOK},
+		{name: "max attempts", method: "PUT", fail: 5, attempts: 3, status: http.StatusServiceUnavailable},
+		{name: "non idempotent", method: "POST", fail: 1, attempts: 1, status: http.StatusServiceUnavailable},
+		{name: "allow non idempotent", method: "POST", fail: 1, attempts: 2, status: http.StatusOK,
+			opts: []CallOption{RetryCallOption(&RetryPolicy{MaxAttempts: 2, RetryableStatus: []int{503}, AllowNonIdempotent: true})}},
+		{name: "retry by reason", method: "GET", fail: 1, reason: "BUSY", attempts: 2, status: http.StatusOK,
+			opts: []CallOption{RetryCallOption(&RetryPolicy{MaxAttempts: 2, RetryableReasons: []string{"BUSY"}})}},
+		{name: "disabled", method: "GET", fail: 1, attempts: 1, status: http.StatusServiceUnavailable,
+			opts: []CallOption{RetryCallOption(&RetryPolicy{})}},
+	}
+	for _, tt := range tests {
+		attempts, bodies, fail, reason = 0, nil, tt.fail, tt.reason
+		var retries int
+		reply := new(testReply)
+		status, err := cli.Invoke(context.Background(), tt.method, "/", &testReply{Message: "mango"}, reply,
+			append(tt.opts, retriesCallOption{retries: &retries})...)
+		if (err != nil) != (status != http.StatusOK) || status != tt.status || attempts != tt.attempts || retries != tt.attempts-1 {
+			t.Errorf("%s: status=%d attempts=%d retries=%d err=%v", tt.name, status, attempts, retries, err)
+		}
+		for _, b := range bodies {
+			if b != `{"message":"mango"}` {
+				t.Errorf("%s: body not replayed, got %q", tt.name, b)
+			}
+		}
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := &RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2, Jitter: 0.5}
+	for attempt, expect := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second} {
+		d := p.backoff(attempt + 1)
+		if d < expect/2 || d > expect*3/2 {
+			t.Errorf("attempt %d: backoff %v out of range around %v", attempt+1, d, expect)
+		}
+	}
+}
+
+func TestInvokeError(t *testing.T) {
+	s := New(WithRouter(gin.New()))
+	s.RegisterService(newEchoServiceDesc(), echoServiceImpl{})
+	s.GinEngine().GET("/gateway", func(c *gin.Context) {
+		c.String(http.StatusBadGateway, "<html>bad gateway</html>")
+	})
+	ts := httptest.NewServer(s.GinEngine())
+	defer ts.Close()
+
+	for _, ct := range []string{"application/json", "application/x-msgpack"} {
+		cli, err := NewClient(WithEndpoint(ts.URL), WithContentType(ct))
+		if err != nil {
+			t.Fatal(err)
+		}
+		reply := new(testReply)
+		status, err := cli.Invoke(context.Background(), "POST", "/echo", &testReply{Message: "fail"}, reply)
+		e, ok := err.(errors.Error)
+		if !ok || status != http.StatusBadRequest {
+			t.Fatalf("%s: expect errors.Error, got status=%d err=%v", ct, status, err)
+		}
+		if e.Code() != 1 || e.HttpStatus() != http.StatusBadRequest || e.Reason() != "ECHO_FAILED" || e.Message() != "echo failed" {
+			t.Errorf("%s: unexpected error %v", ct, e)
+		}
+		if reply.Message != "" {
+			t.Errorf("%s: reply should not be decoded, got %+v", ct, reply)
+		}
+	}
+
+	cli, _ := NewClient(WithEndpoint(ts.URL))
+	status, err := cli.Invoke(context.Background(), "GET", "/gateway", nil, nil)
+	e, ok := err.(errors.Error)
+	if !ok || status != http.StatusBadGateway || e.Reason() != errors.UnknownReason || e.HttpStatus() != http.StatusBadGateway {
+		t.Errorf("unexpected error status=%d err=%v", status, err)
+	}
+}
+
+func TestErrorFormats(t *testing.T) {
+	tests := []struct {
+		option      Option
+		contentType string
+		body        []string
+	}{
+		{WithEncodeErrorFunc(DefaultEncodeErrorFunc), "application/json", []string{`"error":{`, `"reason":"ECHO_FAILED"`, `"metadata":{"field":"message"}`}},
+		{WithProblemDetails(), ProblemContentType, []string{`"type":"about:blank"`, `"title":"Bad Request"`, `"status":400`,
+			`"detail":"echo failed"`, `"instance":"/echo"`, `"reason":"ECHO_FAILED"`, `"code":1`, `"field":"message"`}},
+		{WithStatusErrors(), "application/json", []string{`"code":3`, `"message":"echo failed"`,
+			`"@type":"type.googleapis.com/google.rpc.ErrorInfo"`, `"reason":"ECHO_FAILED"`, `"metadata":{"code":"1","field":"message"}`}},
+	}
+
+	for _, tt := range tests {
+		s := New(WithRouter(gin.New()), tt.option)
+		s.RegisterService(newEchoServiceDesc(), echoServiceImpl{})
+		ts := httptest.NewServer(s.GinEngine())
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/echo", strings.NewReader(`{"message":"fail"}`))
+		r.Header.Set("Content-Type", "application/json")
+		s.GinEngine().ServeHTTP(w, r)
+		if ct := w.Header().Get("Content-Type"); w.Code != http.StatusBadRequest || !strings.HasPrefix(ct, tt.contentType) {
+			t.Errorf("%s: unexpected response %d %s", tt.contentType, w.Code, ct)
+		}
+		for _, member := range tt.body {
+			if !strings.Contains(w.Body.String(), member) {
+				t.Errorf("%s: %s not found in %s", tt.contentType, member, w.Body.String())
+			}
+		}
+
+		// 客户端可以解析任意一种格式
+		for _, ct := range []string{"application/json", "application/x-msgpack"} {
+			cli, _ := NewClient(WithEndpoint(ts.URL), WithContentType(ct))
+			_, err := cli.Invoke(context.Background(), "POST", "/echo", &testReply{Message: "fail"}, new(testReply))
+			e, ok := err.(errors.Error)
+			if !ok || e.Code() != 1 || e.Reason() != "ECHO_FAILED" || e.Message() != "echo failed" ||
+				e.HttpStatus() != http.StatusBadRequest || len(e.Metadata()) != 1 || e.Metadata()["field"] != "message" {
+				t.Errorf("%s %s: unexpected error %v", tt.contentType, ct, err)
+			}
+		}
+		ts.Close()
+	}
+}
+
+func TestClientInterceptors(t *testing.T) {
+	var auth string
+	s := New(WithRouter(gin.New()))
+	s.Middleware(func(ctx context.Context, req interface{}, next Handler) (interface{}, error) {
+		tr, _ := FromServerContext(ctx)
+		auth = tr.RequestHeader().Get("Authorization")
+		tr.ReplyHeader().Set("X-Server", "mangokit")
+		return next(ctx, req)
+	})
+	s.RegisterService(newEchoServiceDesc(), echoServiceImpl{})
+	ts := httptest.NewServer(s.GinEngine())
+	defer ts.Close()
+
+	var trace []string
+	logging := func(ctx context.Context, req interface{}, next Handler) (interface{}, error) {
+		tr, _ := FromClientContext(ctx)
+		trace = append(trace, "before "+tr.Method()+" "+tr.Path()+" "+tr.Operation())
+		reply, err := next(ctx, req)
+		trace = append(trace, fmt.Sprintf("after %d %s %s", tr.StatusCode(), tr.ReplyHeader().Get("X-Server"), reply.(*testReply).Message))
+		return reply, err
+	}
+	token := func(ctx context.Context, req interface{}, next Handler) (interface{}, error) {
+		tr, _ := FromClientContext(ctx)
+		tr.RequestHeader().Set("Authorization", "Bearer token")
+		return next(ctx, &testReply{Message: req.(*testReply).Message + "!"})
+	}
+	cli, err := NewClient(WithEndpoint(ts.URL), WithInterceptors(logging, token))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply := new(testReply)
+	if _, err = cli.Invoke(context.Background(), "POST", "/echo", &testReply{Message: "mango"}, reply,
+		OperationCallOption("/test.Echo/Echo")); err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"before POST /echo /test.Echo/Echo", "after 200 mangokit echo mango!"}
+	if !reflect.DeepEqual(trace, expect) || auth != "Bearer token" {
+		t.Errorf("unexpected trace %v, auth %q", trace, auth)
+	}
+
+	cached := func(ctx context.Context, req interface{}, next Handler) (interface{}, error) {
+		tr, _ := FromClientContext(ctx)
+		reply := tr.Reply().(*testReply)
+		reply.Message = "cached"
+		return reply, nil
+	}
+	cli, _ = NewClient(WithEndpoint("http://127.0.0.1:0"), WithInterceptors(cached))
+	reply = new(testReply)
+	status, err := cli.Invoke(context.Background(), "POST", "/echo", &testReply{Message: "mango"}, reply)
+	if err != nil || status != http.StatusOK || reply.Message != "cached" {
+		t.Errorf("short circuit: status=%d reply=%+v err=%v", status, reply, err)
+	}
+}
+
+func TestClientBalancer(t *testing.T) {
+	hits := make(map[string]int)
+	var servers []string
+	for _, name := range []string{"a", "b"} {
+		name := name
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits[name]++
+			w.Write([]byte(`{"message":"` + name + `"}`))
+		}))
+		defer ts.Close()
+		servers = append(servers, ts.URL)
+	}
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	b := balancer.New(balancer.RoundRobin(), balancer.WithEjection(1, time.Minute))
+	cli, err := NewClient(WithResolver(resolver.NewStatic(append(servers, down.URL)...)), WithBalancer(b),
+		WithRetry(&RetryPolicy{MaxAttempts: 2, RetryOnNetworkError: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	for i := 0; i < 6; i++ {
+		if _, err = cli.Invoke(context.Background(), "GET", "/", nil, new(testReply)); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+	if hits["a"] != 3 || hits["b"] != 3 {
+		t.Errorf("unexpected hits %v", hits)
+	}
+
+	cli, _ = NewClient(WithResolver(resolver.NewStatic()))
+	defer cli.Close()
+	_, err = cli.Invoke(context.Background(), "GET", "/", nil, nil)
+	if e, ok := err.(errors.Error); !ok || e.Reason() != NoEndpointReason {
+		t.Errorf("expect no endpoint error, got %v", err)
+	}
+}
+
+func TestClientCircuitBreaker(t *testing.T) {
+	var hits int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	var states []breaker.State
+	group := breaker.NewGroup(breaker.WithMinRequests(2), breaker.WithStateChange(func(name string, from, to breaker.State) {
+		states = append(states, to)
+	}))
+	cli, err := NewClient(WithEndpoint(ts.URL), WithCircuitBreaker(group, BreakerPerOperation))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err = cli.Invoke(context.Background(), "GET", "/", nil, nil, OperationCallOption("/test.Echo/Get"))
+	}
+	if e, ok := err.(errors.Error); !ok || e.Reason() != BreakerOpenReason || e.HttpStatus() != http.StatusServiceUnavailable {
+		t.Errorf("expect circuit breaker open error, got %v", err)
+	}
+	if hits != 2 || !reflect.DeepEqual(states, []breaker.State{breaker.StateOpen}) {
+		t.Errorf("unexpected hits %d states %v", hits, states)
+	}
+
+	// 其他接口不受影响
+	if _, err = cli.Invoke(context.Background(), "GET", "/", nil, nil, OperationCallOption("/test.Echo/List")); err == nil || err.(errors.Error).Reason() == BreakerOpenReason {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	s := New(WithRouter(gin.New()))
+	s.Middleware(RateLimit(0.5, 2, WithLimitKey(KeyClientIP())))
+	s.RegisterService(newTestServiceDesc(), testServiceImpl{})
+
+	request := func(ip string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/admin/user", nil)
+		r.RemoteAddr = ip + ":1234"
+		s.GinEngine().ServeHTTP(w, r)
+		return w
+	}
+	for i := 0; i < 2; i++ {
+		if w := request("10.0.0.1"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: unexpected status %d", i, w.Code)
+		}
+	}
+	w := request("10.0.0.1")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "2" ||
+		!strings.Contains(w.Body.String(), RateLimitReason) {
+		t.Errorf("expect rate limited, got %d %q %s", w.Code, w.Header().Get("Retry-After"), w.Body.String())
+	}
+	if w = request("10.0.0.2"); w.Code != http.StatusOK {
+		t.Errorf("other client should not be limited, got %d", w.Code)
+	}
+
+	for _, args := range []struct {
+		rate  float64
+		burst int
+	}{{0, 1}, {-1, 1}, {math.NaN(), 1}, {1, 0}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RateLimit(%v, %d) should panic", args.rate, args.burst)
+				}
+			}()
+			RateLimit(args.rate, args.burst)
+		}()
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	limit := ConcurrencyLimit(1, WithLimitKey(KeyHeader("X-Api-Key")))
+	ctx := func(key string) context.Context {
+		header := http.Header{"X-Api-Key": []string{key}}
+		return NewServerContext(context.Background(), &Transport{reqHeader: header, replyHeader: make(http.Header)})
+	}
+
+	entered, release := make(chan struct{}), make(chan struct{})
+	go limit(ctx("a"), nil, func(ctx context.Context, req interface{}) (interface{}, error) {
+		close(entered)
+		<-release
+		return nil, nil
+	})
+	<-entered
+	noop := func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil }
+
+	c := ctx("a")
+	_, err := limit(c, nil, noop)
+	if e, ok := err.(errors.Error); !ok || e.HttpStatus() != http.StatusServiceUnavailable || e.Reason() != ConcurrencyLimitReason {
+		t.Errorf("expect concurrency limited, got %v", err)
+	}
+	if tr, _ := FromServerContext(c); tr.ReplyHeader().Get("Retry-After") != "1" {
+		t.Error("Retry-After not set")
+	}
+	if _, err = limit(ctx("b"), nil, noop); err != nil {
+		t.Errorf("other key should not be limited, got %v", err)
+	}
+	close(release)
+}
+
+func TestAdaptiveLimit(t *testing.T) {
+	var cpu float64
+	l := newAdaptiveLimiter(&adaptiveOptions{
+		minLimit:     1,
+		maxLimit:     4,
+		tolerance:    2,
+		window:       time.Minute,
+		cpuThreshold: 0.8,
+		cpuUsage:     func() float64 { return cpu },
+	})
+	// 并发请求直到被拒绝, 所有请求以rtt的耗时结束, 返回通过的请求数量
+	probe := func(rtt time.Duration) int {
+		n := 0
+		for l.acquire() {
+			n++
+		}
+		for i := 0; i < n; i++ {
+			l.release(rtt)
+		}
+		l.cpuAt = time.Time{}
+		return n
+	}
+
+	if n := probe(10 * time.Millisecond); n != 1 {
+		t.Fatalf("initial limit should be min, got %d", n)
+	}
+	for i := 0; i < 5; i++ {
+		probe(10 * time.Millisecond)
+	}
+	if n := probe(10 * time.Millisecond); n != 4 {
+		t.Errorf("limit should grow to max, got %d", n)
+	}
+
+	probe(50 * time.Millisecond)
+	if n := probe(10 * time.Millisecond); n >= 4 {
+		t.Errorf("limit should shrink when latency rises, got %d", n)
+	}
+
+	cpu = 0.9
+	for i := 0; i < 20; i++ {
+		probe(10 * time.Millisecond)
+	}
+	if n := probe(10 * time.Millisecond); n != 1 {
+		t.Errorf("limit should shrink to min under high cpu, got %d", n)
+	}
+
+	limit := AdaptiveLimit(WithLimitRange(1, 1))
+	entered, release := make(chan struct{}), make(chan struct{})
+	go limit(context.Background(), nil, func(ctx context.Context, req interface{}) (interface{}, error) {
+		close(entered)
+		<-release
+		return nil, nil
+	})
+	<-entered
+	_, err := limit(context.Background(), nil, func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil })
+	if e, ok := err.(errors.Error); !ok || e.Reason() != LoadSheddingReason {
+		t.Errorf("expect load shedding, got %v", err)
+	}
+	close(release)
+}
+
+func TestAuth(t *testing.T) {
+	secret := []byte("secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var principal *Principal
+	s := New(WithRouter(gin.New()))
+	s.GroupAuth("/admin", JWT(func(alg, kid string) (interface{}, error) {
+		if kid == "rsa" {
+			return &rsaKey.PublicKey, nil
+		}
+		return secret, nil
+	}, WithIssuer("mangokit"), WithAuthSkip("GET /administrator"), WithAuthorizer(func(ctx context.Context, p *Principal) bool {
+		return p.Claims.GetString("role") == "admin"
+	})))
+	s.RegisterService(newTestServiceDesc(), testServiceImpl{}, WithServiceMiddleware(func(ctx context.Context, req interface{}, next Handler) (interface{}, error) {
+		principal, _ = PrincipalFromContext(ctx)
+		return next(ctx, req)
+	}))
+	ts := httptest.NewServer(s.GinEngine())
+	defer ts.Close()
+	cli, _ := NewClient(WithEndpoint(ts.URL))
+
+	sign := func(alg, kid string, key interface{}, claims Claims) string {
+		token, err := SignJWTWithKid(alg, kid, key, claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+	tests := []struct {
+		name   string
+		token  string
+		reason string
+	}{
+		{"hmac", sign("HS256", "", secret, Claims{"sub": "mango", "iss": "mangokit", "role": "admin", "exp": exp}), ""},
+		{"rsa", sign("RS512", "rsa", rsaKey, Claims{"sub": "mango", "iss": "mangokit", "role": "admin"}), ""},
+		{"missing", "", UnauthorizedReason},
+		{"bad signature", sign("HS256", "", []byte("other"), Claims{"sub": "mango"}), UnauthorizedReason},
+		{"alg confusion", sign("HS256", "rsa", secret, Claims{"sub": "mango", "iss": "mangokit"}), UnauthorizedReason},
+		{"expired", sign("HS384", "", secret, Claims{"sub": "mango", "iss": "mangokit", "exp": time.Now().Add(-time.Minute).Unix()}), TokenExpiredReason},
+		{"issuer", sign("HS256", "", secret, Claims{"sub": "mango", "iss": "other", "role": "admin"}), UnauthorizedReason},
+		{"forbidden", sign("HS512", "", secret, Claims{"sub": "mango", "iss": "mangokit", "role": "guest"}), ForbiddenReason},
+	}
+	for _, tt := range tests {
+		principal = nil
+		var opts []CallOption
+		if tt.token != "" {
+			opts = append(opts, BearerTokenCallOption(tt.token))
+		}
+		_, err := cli.Invoke(context.Background(), "GET", "/admin/user", nil, nil, opts...)
+		if tt.reason == "" {
+			if err != nil || principal == nil || principal.Subject != "mango" || principal.Scheme != SchemeJWT {
+				t.Errorf("%s: principal=%+v err=%v", tt.name, principal, err)
+			}
+			continue
+		}
+		if e, ok := err.(errors.Error); !ok || e.Reason() != tt.reason {
+			t.Errorf("%s: expect %s, got %v", tt.name, tt.reason, err)
+		}
+	}
+
+	if _, err = cli.Invoke(context.Background(), "GET", "/administrator", nil, nil); err != nil {
+		t.Errorf("skipped route: %v", err)
+	}
+	if w := serve(s, "GET", "/admin/user"); w.Header().Get("WWW-Authenticate") != "Bearer" {
+		t.Errorf("unexpected challenge %q", w.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestAPIKeyAndBasicAuth(t *testing.T) {
+	s := New(WithRouter(gin.New()))
+	var principal *Principal
+	s.GroupAuth("/admin", APIKey(StaticAPIKeys(map[string]string{"k1": "service-a"}), WithAPIKeyHeader("X-Token")))
+	s.GroupAuth("/administrator", BasicAuth(StaticAccounts(map[string]string{"mango": "pass"}), WithRealm("admin")))
+	s.RegisterService(newTestServiceDesc(), testServiceImpl{}, WithServiceMiddleware(func(ctx context.Context, req interface{}, next Handler) (interface{}, error) {
+		principal, _ = PrincipalFromContext(ctx)
+		return next(ctx, req)
+	}))
+	ts := httptest.NewServer(s.GinEngine())
+	defer ts.Close()
+	cli, _ := NewClient(WithEndpoint(ts.URL))
+
+	if _, err := cli.Invoke(context.Background(), "GET", "/admin/user", nil, nil, APIKeyHeaderCallOption("X-Token", "k1")); err != nil ||
+		principal == nil || principal.Subject != "service-a" || principal.Scheme != SchemeAPIKey {
+		t.Errorf("api key: principal=%+v err=%v", principal, err)
+	}
+	if _, err := cli.Invoke(context.Background(), "GET", "/admin/user", nil, nil, APIKeyCallOption("k1")); err == nil {
+		t.Error("api key in wrong header should be rejected")
+	}
+
+	principal = nil
+	if _, err := cli.Invoke(context.Background(), "GET", "/administrator", nil, nil, BasicAuthCallOption("mango", "pass"),
+		HeadersCallOption(http.Header{"X-Request-Id": []string{"1"}})); err != nil || principal == nil || principal.Subject != "mango" {
+		t.Errorf("basic auth: principal=%+v err=%v", principal, err)
+	}
+	status, err := cli.Invoke(context.Background(), "GET", "/administrator", nil, nil, BasicAuthCallOption("mango", "wrong"))
+	if e, ok := err.(errors.Error); !ok || status != http.StatusUnauthorized || e.Reason() != UnauthorizedReason {
+		t.Errorf("expect unauthorized, got %d %v", status, err)
+	}
+	if w := serve(s, "GET", "/administrator"); w.Header().Get("WWW-Authenticate") != `Basic realm="admin"` {
+		t.Errorf("unexpected challenge %q", w.Header().Get("WWW-Authenticate"))
+	}
+
+	// validator返回nil时认为校验失败
+	auth := APIKey(func(ctx context.Context, key string) (*Principal, error) { return nil, nil })
+	ctx := NewServerContext(context.Background(), &Transport{reqHeader: http.Header{"X-Api-Key": []string{"k1"}}, replyHeader: http.Header{}})
+	_, err = auth(ctx)
+	if e, ok := err.(errors.Error); !ok || e.Reason() != UnauthorizedReason {
+		t.Errorf("nil principal: expect unauthorized, got %v", err)
+	}
+
+	// validator返回的Principal不会被修改
+	shared := &Principal{Subject: "service-a"}
+	auth = APIKey(func(ctx context.Context, key string) (*Principal, error) { return shared, nil })
+	if ctx, err = auth(ctx); err != nil || shared.Scheme != "" {
+		t.Errorf("shared principal modified: %+v %v", shared, err)
+	}
+	if p, _ := PrincipalFromContext(ctx); p == nil || p == shared || p.Scheme != SchemeAPIKey {
+		t.Errorf("unexpected principal %+v", p)
+	}
+}
+
+func TestAuthBeforeBind(t *testing.T) {
+	s := New(WithRouter(gin.New()))
+	s.Auth(JWT(func(alg, kid string) (interface{}, error) { return []byte("secret"), nil }))
+	s.RegisterService(newEchoServiceDesc(), echoServiceImpl{})
+
+	// 未认证的请求即使参数错误也返回401, 认证失败的原因不会返回给客户端
+	for _, token := range []string{"", "Bearer " + mustSign(t, []byte("other"))} {
+		r := httptest.NewRequest("POST", "/echo", strings.NewReader("{"))
+		r.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			r.Header.Set("Authorization", token)
+		}
+		w := httptest.NewRecorder()
+		s.GinEngine().ServeHTTP(w, r)
+		if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), `"message":"unauthorized"`) {
+			t.Errorf("unexpected response %d %s", w.Code, w.Body.String())
+		}
+	}
+
+	r := httptest.NewRequest("POST", "/echo", strings.NewReader("{"))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Authorization", "Bearer "+mustSign(t, []byte("secret")))
+	w := httptest.NewRecorder()
+	s.GinEngine().ServeHTTP(w, r)
+	if w.Code == http.StatusUnauthorized {
+		t.Errorf("authenticated request should reach binding, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func mustSign(t *testing.T, key []byte) string {
+	t.Helper()
+	token, err := SignJWT("HS256", key, Claims{"sub": "mango"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
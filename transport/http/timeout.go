@@ -0,0 +1,39 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// TimeoutHeader 传递请求剩余超时时间的请求头, 单位为毫秒
+// 使用剩余时间而不是绝对时间, 避免客户端与服务端时钟不一致
+const TimeoutHeader = "X-Request-Timeout"
+
+// setTimeoutHeader 如果ctx设置了deadline, 将剩余时间写入请求头
+func setTimeoutHeader(ctx context.Context, header http.Header) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return
+	}
+	remaining := time.Until(deadline).Milliseconds()
+	if remaining < 1 {
+		remaining = 1
+	}
+	header.Set(TimeoutHeader, strconv.FormatInt(remaining, 10))
+}
+
+// parseTimeoutHeader 解析请求头中的超时时间, 不存在或不合法时返回false
+func parseTimeoutHeader(header http.Header) (time.Duration, bool) {
+	v := header.Get(TimeoutHeader)
+	if v == "" {
+		return 0, false
+	}
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || ms <= 0 {
+		return 0, false
+	}
+
+	return time.Duration(ms) * time.Millisecond, true
+}
@@ -0,0 +1,72 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/mangohow/mangokit/errors"
+	"github.com/mangohow/mangokit/tracing"
+)
+
+// TraceIDKey 错误Metadata中trace id的key
+const TraceIDKey = "trace_id"
+
+// spanName 优先使用proto完整方法名, 否则使用 请求方法 + 路由
+func spanName(operation, method, route string) string {
+	if operation != "" {
+		return operation
+	}
+
+	return method + " " + route
+}
+
+// startServerSpan 从请求头中解析W3C traceparent, 创建服务端span
+func startServerSpan(ctx context.Context, tracer *tracing.Tracer, tr *Transport) (context.Context, *tracing.Span) {
+	if sc, ok := tracing.Extract(tr.RequestHeader()); ok {
+		ctx = tracing.ContextWithRemoteSpanContext(ctx, sc)
+	}
+	ctx, span := tracer.Start(ctx, spanName(tr.Operation(), tr.Method(), tr.PathTemplate()), tracing.SpanKindServer)
+	span.SetAttribute("http.method", tr.Method())
+	span.SetAttribute("http.route", tr.PathTemplate())
+
+	return ctx, span
+}
+
+// startClientSpan 创建客户端span, 需要使用tracing.Inject将trace信息写入请求头, http.url在选择实例后设置
+func startClientSpan(ctx context.Context, tracer *tracing.Tracer, bco *BeforeCallInfo, method string) (context.Context, *tracing.Span) {
+	ctx, span := tracer.Start(ctx, spanName(bco.Operation, method, bco.PathTemplate), tracing.SpanKindClient)
+	span.SetAttribute("http.method", method)
+
+	return ctx, span
+}
+
+func endSpan(span *tracing.Span, status int, err error) {
+	if err != nil {
+		span.RecordError(err)
+		if e, ok := err.(errors.Error); ok {
+			status = int(e.HttpStatus())
+		}
+	}
+	if status != 0 {
+		span.SetAttribute("http.status_code", strconv.Itoa(status))
+	}
+	span.End()
+}
+
+// withTraceID 将trace id添加到错误的Metadata中, 会复制一份错误, 不会修改共享的错误变量
+// 被包装的Error以及其他error同样使用errors.Convert转换后添加
+func withTraceID(err error, traceID string) error {
+	if traceID == "" {
+		return err
+	}
+
+	return errors.Clone(errors.Convert(err)).WithMetadata(TraceIDKey, traceID)
+}
+
+// traceHeader 服务端在响应头中返回trace id, 便于排查问题
+const traceHeader = "X-Trace-Id"
+
+func setTraceHeader(header http.Header, span *tracing.Span) {
+	header.Set(traceHeader, span.SpanContext().TraceID.String())
+}
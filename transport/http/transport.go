@@ -0,0 +1,139 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/mangohow/mangokit/metrics"
+)
+
+// Transport 当前请求的传输层信息, 由Server在调用中间件前注入到context中
+type Transport struct {
+	operation    string
+	serviceName  string
+	method       string
+	pathTemplate string
+	reqHeader    http.Header
+	replyHeader  http.Header
+	statusCode   int
+	// metrics WithMetrics设置的Registry, Server已经记录了指标, Metrics中间件使用相同的Registry时不再重复记录
+	metrics *metrics.Registry
+}
+
+// Operation proto中定义的完整方法名, 例如 /helloworld.v1.Greeter/SayHello
+func (t *Transport) Operation() string {
+	return t.operation
+}
+
+// ServiceName go service名, 例如 Greeter
+func (t *Transport) ServiceName() string {
+	return t.serviceName
+}
+
+// Method http请求方法
+func (t *Transport) Method() string {
+	return t.method
+}
+
+// PathTemplate 路由模板, 例如 /user/:id
+func (t *Transport) PathTemplate() string {
+	return t.pathTemplate
+}
+
+// RequestHeader 请求头
+func (t *Transport) RequestHeader() http.Header {
+	return t.reqHeader
+}
+
+// ReplyHeader 响应头, 在handler返回前设置的header会写入响应
+func (t *Transport) ReplyHeader() http.Header {
+	return t.replyHeader
+}
+
+// StatusCode 请求成功时的响应码
+func (t *Transport) StatusCode() int {
+	return t.statusCode
+}
+
+// SetStatusCode 设置请求成功时的响应码, 在handler中调用可以覆盖注册时设置的响应码
+func (t *Transport) SetStatusCode(code int) {
+	t.statusCode = code
+}
+
+type serverTransportKey struct{}
+
+// NewServerContext 将Transport存入context
+func NewServerContext(ctx context.Context, tr *Transport) context.Context {
+	return context.WithValue(ctx, serverTransportKey{}, tr)
+}
+
+// FromServerContext 从context中获取Transport
+func FromServerContext(ctx context.Context) (*Transport, bool) {
+	tr, ok := ctx.Value(serverTransportKey{}).(*Transport)
+	return tr, ok
+}
+
+// ClientTransport 客户端请求的传输层信息, 由Client在调用拦截器前注入到context中
+type ClientTransport struct {
+	operation    string
+	method       string
+	path         string
+	pathTemplate string
+	reqHeader    http.Header
+	replyHeader  http.Header
+	statusCode   int
+	reply        interface{}
+}
+
+// Operation proto中定义的完整方法名, 由生成代码通过OperationCallOption设置
+func (t *ClientTransport) Operation() string {
+	return t.operation
+}
+
+// Method http请求方法
+func (t *ClientTransport) Method() string {
+	return t.method
+}
+
+// Path 请求路径, 包含query参数, 例如 /user/1?name=mango
+func (t *ClientTransport) Path() string {
+	return t.path
+}
+
+// PathTemplate 路由模板, 由生成代码通过PathTemplateCallOption设置
+func (t *ClientTransport) PathTemplate() string {
+	return t.pathTemplate
+}
+
+// RequestHeader 请求头, 在调用handler之前修改会作用于发出的请求
+func (t *ClientTransport) RequestHeader() http.Header {
+	return t.reqHeader
+}
+
+// ReplyHeader 响应头, 调用handler之后可用
+func (t *ClientTransport) ReplyHeader() http.Header {
+	return t.replyHeader
+}
+
+// StatusCode 响应码, 调用handler之后可用
+func (t *ClientTransport) StatusCode() int {
+	return t.statusCode
+}
+
+// Reply 调用Invoke时传入的响应值, 拦截器中断请求时可以直接填充该值
+func (t *ClientTransport) Reply() interface{} {
+	return t.reply
+}
+
+type clientTransportKey struct{}
+
+// NewClientContext 将ClientTransport存入context
+func NewClientContext(ctx context.Context, tr *ClientTransport) context.Context {
+	return context.WithValue(ctx, clientTransportKey{}, tr)
+}
+
+// FromClientContext 从context中获取ClientTransport
+func FromClientContext(ctx context.Context) (*ClientTransport, bool) {
+	tr, ok := ctx.Value(clientTransportKey{}).(*ClientTransport)
+	return tr, ok
+}
@@ -0,0 +1,287 @@
+package http
+
+import (
+	"encoding"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	enumType            = reflect.TypeOf((*protoreflect.Enum)(nil)).Elem()
+	protoMessageType    = reflect.TypeOf((*proto.Message)(nil)).Elem()
+)
+
+// EncodeURL 使用obj中param tag的字段填充pattern中的路径参数, query为true时使用form tag的字段生成query参数
+// pattern中的参数可以位于任意位置, 例如 /user/:id/books/:book.name 以及 /static/*filepath
+// 嵌套message中的字段使用 a.b 的形式表示, 与bindParam以及bindQuery的解析规则一致, 值为空的路径参数会被忽略
+func EncodeURL(pattern string, obj interface{}, query bool) string {
+	if pattern == "" || obj == nil {
+		return ""
+	}
+
+	params := make(url.Values)
+	collectValues(reflect.ValueOf(obj), ParamKey, "", params)
+
+	var (
+		builder = &strings.Builder{}
+		used    = make(map[string]struct{})
+		parts   []string
+	)
+	for _, seg := range strings.Split(pattern, "/") {
+		switch {
+		case strings.HasPrefix(seg, ":"):
+			name := seg[1:]
+			used[name] = struct{}{}
+			// 值为空的参数与之前的版本一样忽略该段, 避免生成 /user//books
+			if v := params.Get(name); v != "" {
+				parts = append(parts, escapePath(v))
+			}
+		case strings.HasPrefix(seg, "*"):
+			// catch-all参数可以包含 /, 分别对每一段进行转义
+			name := seg[1:]
+			used[name] = struct{}{}
+			if v := strings.TrimPrefix(params.Get(name), "/"); v != "" {
+				for _, part := range strings.Split(v, "/") {
+					parts = append(parts, escapePath(part))
+				}
+			}
+		default:
+			parts = append(parts, seg)
+		}
+	}
+	path := strings.Join(parts, "/")
+	if path == "" && strings.HasPrefix(pattern, "/") {
+		path = "/"
+	}
+	builder.WriteString(path)
+
+	// 如果一个字段既添加了param tag 又添加了form tag，则需要忽略form tag，因为已经在param参数中进行了设置
+	if query {
+		encodeQuery(obj, builder, used)
+	}
+
+	return builder.String()
+}
+
+// escapePath 转义路径参数, gin解析路径参数时会将 + 解析为空格, 因此 + 也需要转义
+func escapePath(s string) string {
+	return strings.ReplaceAll(url.PathEscape(s), "+", "%2B")
+}
+
+// EncodeURLFromForm 使用obj中form tag的字段生成query参数
+func EncodeURLFromForm(pattern string, obj interface{}) string {
+	if pattern == "" || obj == nil {
+		return ""
+	}
+	builder := &strings.Builder{}
+	builder.WriteString(pattern)
+	encodeQuery(obj, builder, nil)
+
+	return builder.String()
+}
+
+// encodeQuery 生成query参数, repeated字段生成多个同名参数, 参数按照名称排序, 空字符串和nil字段会被忽略
+func encodeQuery(obj interface{}, builder *strings.Builder, exclude map[string]struct{}) {
+	values := make(url.Values)
+	collectValues(reflect.ValueOf(obj), FormKey, "", values)
+	for k := range exclude {
+		delete(values, k)
+	}
+	if len(values) == 0 {
+		return
+	}
+
+	builder.WriteByte('?')
+	builder.WriteString(values.Encode())
+}
+
+// tagName 获取tag中的参数名, 忽略 ,default=xxx 等选项
+func tagName(field reflect.StructField, tagKey string) string {
+	tag := field.Tag.Get(tagKey)
+	if i := strings.IndexByte(tag, ','); i != -1 {
+		tag = tag[:i]
+	}
+	if tag == "-" {
+		return ""
+	}
+	return tag
+}
+
+// isScalar 判断类型是否作为单个值编码, 而不是展开为嵌套字段
+func isScalar(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return true
+	}
+	pt := reflect.PointerTo(t)
+	return t == timeType || pt.Implements(textMarshalerType) || pt.Implements(textUnmarshalerType) || isWrapper(pt)
+}
+
+// isWrapper 判断是否为google.protobuf中的XxxValue包装类型
+func isWrapper(t reflect.Type) bool {
+	if !t.Implements(protoMessageType) {
+		return false
+	}
+	m, ok := reflect.Zero(t).Interface().(proto.Message)
+	if !ok {
+		return false
+	}
+	name := m.ProtoReflect().Descriptor().FullName()
+	return name.Parent() == "google.protobuf" && strings.HasSuffix(string(name.Name()), "Value") &&
+		name.Name() != "Value" && name.Name() != "ListValue"
+}
+
+// collectValues 收集结构体中带有tagKey的字段值, 嵌套结构体的字段使用 prefix.name 作为key
+// 未添加tag的嵌套结构体字段与gin的行为一致, 直接展开到当前层级
+func collectValues(v reflect.Value, tagKey, prefix string, values url.Values) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+		name := tagName(field, tagKey)
+
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if !isScalar(ft) {
+			if name == "" {
+				collectValues(fv, tagKey, prefix, values)
+			} else {
+				collectValues(fv, tagKey, prefix+name+".", values)
+			}
+			continue
+		}
+		if name == "" {
+			continue
+		}
+
+		key := prefix + name
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+			for j := 0; j < fv.Len(); j++ {
+				if s, ok := formatField(fv.Index(j), field); ok {
+					values.Add(key, s)
+				}
+			}
+			continue
+		}
+		if s, ok := formatField(fv, field); ok && s != "" {
+			values.Add(key, s)
+		}
+	}
+}
+
+// formatField 将字段值转换为字符串, time.Time字段使用time_format tag指定的格式, 与bindQuery的解析规则一致
+func formatField(v reflect.Value, field reflect.StructField) (string, bool) {
+	format := field.Tag.Get("time_format")
+	if format == "" || indirectType(v.Type()) != timeType {
+		return formatValue(v)
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+
+	t := v.Interface().(time.Time)
+	if t.IsZero() {
+		return "", true
+	}
+	switch strings.ToLower(format) {
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10), true
+	case "unixmilli":
+		return strconv.FormatInt(t.UnixMilli(), 10), true
+	case "unixmicro":
+		return strconv.FormatInt(t.UnixMicro(), 10), true
+	case "unixnano":
+		return strconv.FormatInt(t.UnixNano(), 10), true
+	}
+	if utc, _ := strconv.ParseBool(field.Tag.Get("time_utc")); utc {
+		t = t.UTC()
+	}
+	if name := field.Tag.Get("time_location"); name != "" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			t = t.In(loc)
+		}
+	}
+
+	return t.Format(format), true
+}
+
+// formatValue 将字段值转换为字符串, proto枚举使用枚举名, time.Time使用RFC3339格式, nil返回false
+func formatValue(v reflect.Value) (string, bool) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", false
+		}
+		if v.Type().Implements(enumType) || v.Type().Implements(textMarshalerType) || isWrapper(v.Type()) {
+			break
+		}
+		v = v.Elem()
+	}
+
+	if v.Type() == timeType {
+		// 零值时间与空字符串相同, 不生成参数
+		if t := v.Interface().(time.Time); !t.IsZero() {
+			return t.Format(time.RFC3339Nano), true
+		}
+		return "", true
+	}
+	if isWrapper(v.Type()) {
+		return formatValue(v.Elem().FieldByName("Value"))
+	}
+	if e, ok := v.Interface().(protoreflect.Enum); ok {
+		if ev := e.Descriptor().Values().ByNumber(e.Number()); ev != nil {
+			return string(ev.Name()), true
+		}
+		return strconv.FormatInt(int64(e.Number()), 10), true
+	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		text, err := m.MarshalText()
+		return string(text), err == nil
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 32), true
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64), true
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true
+	case reflect.String:
+		return v.String(), true
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes()), true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,282 @@
+package http
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type urlBook struct {
+	Name string   `form:"name" param:"name"`
+	Tags []string `form:"tags"`
+}
+
+type urlRequest struct {
+	ID     int64                                    `param:"id" form:"id"`
+	Path   string                                   `param:"path"`
+	Kind   descriptorpb.FieldDescriptorProto_Type   `form:"kind"`
+	Kinds  []descriptorpb.FieldDescriptorProto_Type `form:"kinds"`
+	Query  string                                   `form:"q"`
+	Page   *int32                                   `form:"page"`
+	Title  *wrapperspb.StringValue                  `form:"title"`
+	At     time.Time                                `form:"at"`
+	Book   *urlBook                                 `form:"book" param:"book"`
+	Empty  string                                   `form:"empty"`
+	Ignore string
+}
+
+func TestEncodeURL(t *testing.T) {
+	page := int32(2)
+	req := &urlRequest{
+		ID:    9223372036854775807,
+		Path:  "/a b/c?d",
+		Kind:  descriptorpb.FieldDescriptorProto_TYPE_STRING,
+		Kinds: []descriptorpb.FieldDescriptorProto_Type{descriptorpb.FieldDescriptorProto_TYPE_INT32, 99},
+		Query: "x&y=z",
+		Page:  &page,
+		Title: wrapperspb.String("go"),
+		At:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Book:  &urlBook{Name: "mango/kit", Tags: []string{"b", "a"}},
+	}
+
+	tests := []struct {
+		pattern string
+		query   bool
+		expect  string
+	}{
+		{"/shelves/:id", false, "/shelves/9223372036854775807"},
+		{"/shelves/:id/books/:book.name/*path", false, "/shelves/9223372036854775807/books/mango%2Fkit/a%20b/c%3Fd"},
+		{"/shelves/:id/books", true, "/shelves/9223372036854775807/books?at=2024-01-02T03%3A04%3A05Z&book.name=mango%2Fkit&book.tags=b&book.tags=a" +
+			"&kind=TYPE_STRING&kinds=TYPE_INT32&kinds=99&page=2&q=x%26y%3Dz&title=go"},
+	}
+	for _, tt := range tests {
+		if got := EncodeURL(tt.pattern, req, tt.query); got != tt.expect {
+			t.Errorf("%s:\n got %s\nwant %s", tt.pattern, got, tt.expect)
+		}
+	}
+
+	// 值为空的路径参数被忽略
+	for pattern, expect := range map[string]string{
+		"/shelves/:book.name/books": "/shelves/books",
+		"/shelves/:book.name":       "/shelves",
+		"/static/*path":             "/static",
+	} {
+		if got := EncodeURL(pattern, &urlRequest{}, false); got != expect {
+			t.Errorf("%s: got %s, want %s", pattern, got, expect)
+		}
+	}
+
+	if got := EncodeURLFromForm("/books", &urlRequest{Query: "mango"}); got != "/books?id=0&kind=0&q=mango" {
+		t.Errorf("unexpected form url %s", got)
+	}
+	if got := EncodeURLFromForm("/books", &urlBook{}); got != "/books" {
+		t.Errorf("unexpected empty form url %s", got)
+	}
+}
+
+func TestEncodeURLRoundTrip(t *testing.T) {
+	page := int32(3)
+	in := &urlRequest{
+		ID:    42,
+		Kind:  descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+		Kinds: []descriptorpb.FieldDescriptorProto_Type{descriptorpb.FieldDescriptorProto_TYPE_INT64, descriptorpb.FieldDescriptorProto_TYPE_BYTES},
+		Query: "a b&c",
+		Page:  &page,
+		Title: wrapperspb.String("中文"),
+		At:    time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC),
+		Book:  &urlBook{Name: "mango", Tags: []string{"x", "y"}},
+	}
+
+	var out urlRequest
+	r := gin.New()
+	r.GET("/shelves/:id/books", func(c *gin.Context) {
+		ctx := context.WithValue(context.Background(), "gin-ctx", c)
+		if err := BindVar(ctx, &out); err != nil {
+			t.Error(err)
+		}
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", EncodeURL("/shelves/:id/books", in, true), nil))
+
+	if out.ID != in.ID || out.Kind != in.Kind || !reflect.DeepEqual(out.Kinds, in.Kinds) || out.Query != in.Query ||
+		*out.Page != *in.Page || out.Title.GetValue() != in.Title.GetValue() || !out.At.Equal(in.At) ||
+		!reflect.DeepEqual(out.Book, in.Book) {
+		t.Errorf("round trip mismatch:\n in %+v\nout %+v", in, out)
+	}
+
+	w := httptest.NewRecorder()
+	r = gin.New()
+	r.GET("/books", func(c *gin.Context) {
+		ctx := context.WithValue(context.Background(), "gin-ctx", c)
+		DefaultEncodeErrorFunc(c, BindVar(ctx, &urlRequest{}), logrus.New())
+	})
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/books?kind=TYPE_UNKNOWN", nil))
+	if w.Code != 400 || !strings.Contains(w.Body.String(), BindReason) || !strings.Contains(w.Body.String(), `"parameter":"kind"`) {
+		t.Errorf("unexpected bind error %d %s", w.Code, w.Body.String())
+	}
+}
+
+type node struct {
+	Name  string `form:"name" param:"name"`
+	Child *node
+	Next  *node `form:"next" param:"next"`
+}
+
+type ginCompatRequest struct {
+	Keyword string
+	Page    int       `form:",default=1"`
+	Skip    string    `form:"-"`
+	Day     time.Time `form:"day" time_format:"2006-01-02" time_utc:"1"`
+	Since   time.Time `form:"since" time_format:"unix"`
+	Created *timestamppb.Timestamp
+	Node    *node
+}
+
+func TestBindQuery(t *testing.T) {
+	bind := func(query string, val interface{}) error {
+		q, err := url.ParseQuery(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return bindQuery(q, val)
+	}
+
+	// 递归引用自身的message不会无限递归, 没有参数的嵌套message不会被创建
+	var n node
+	if err := bind("name=a&next.name=b&next.next.name=c", &n); err != nil {
+		t.Fatal(err)
+	}
+	if n.Name != "a" || n.Child != nil || n.Next == nil || n.Next.Name != "b" || n.Next.Next == nil ||
+		n.Next.Next.Name != "c" || n.Next.Next.Next != nil {
+		t.Errorf("unexpected recursive binding %+v", n)
+	}
+
+	var r ginCompatRequest
+	if err := bind("Keyword=mango&Skip=x&day=2024-05-06&since=1700000000", &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Keyword != "mango" || r.Page != 1 || r.Skip != "" || r.Created != nil || r.Node != nil ||
+		!r.Day.Equal(time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)) || r.Since.Unix() != 1700000000 {
+		t.Errorf("unexpected gin compatible binding %+v", r)
+	}
+	if got := EncodeURLFromForm("/search", &ginCompatRequest{Day: r.Day, Since: r.Since}); got != "/search?day=2024-05-06&since=1700000000" {
+		t.Errorf("unexpected time_format encoding %s", got)
+	}
+
+	if err := bind("day=2024/05/06", &r); err == nil {
+		t.Error("expect time_format error")
+	}
+}
+
+type pathRequest struct {
+	ID    uint64                                 `param:"id"`
+	Kind  descriptorpb.FieldDescriptorProto_Type `param:"kind"`
+	Size  *wrapperspb.UInt32Value                `param:"size"`
+	At    time.Time                              `param:"at"`
+	Addr  netip.Addr                             `param:"addr"`
+	Book  *urlBook                               `param:"book"`
+	Path  string                                 `param:"path"`
+	Level uint8                                  `param:"level"`
+}
+
+func TestBindParam(t *testing.T) {
+	const pattern = "/shelves/:id/:kind/:size/:at/:addr/books/:book.name/*path"
+	in := &pathRequest{
+		ID:   18446744073709551615,
+		Kind: descriptorpb.FieldDescriptorProto_TYPE_ENUM,
+		Size: wrapperspb.UInt32(7),
+		At:   time.Date(2024, 5, 6, 7, 8, 9, 0, time.FixedZone("", 8*3600)),
+		Addr: netip.MustParseAddr("::1"),
+		Book: &urlBook{Name: "a/b c"},
+		Path: "dir/file name.txt",
+	}
+
+	var out pathRequest
+	r := gin.New()
+	r.UseRawPath = true
+	r.GET(pattern, func(c *gin.Context) {
+		ctx := context.WithValue(context.Background(), "gin-ctx", c)
+		if err := BindVar(ctx, &out); err != nil {
+			t.Error(err)
+		}
+	})
+	r.GET("/level/:level", func(c *gin.Context) {
+		ctx := context.WithValue(context.Background(), "gin-ctx", c)
+		DefaultEncodeErrorFunc(c, BindVar(ctx, &pathRequest{}), logrus.New())
+	})
+	r.GET("/kind/:kind", func(c *gin.Context) {
+		ctx := context.WithValue(context.Background(), "gin-ctx", c)
+		DefaultEncodeErrorFunc(c, BindVar(ctx, &pathRequest{}), logrus.New())
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", EncodeURL(pattern, in, false), nil))
+	if out.ID != in.ID || out.Kind != in.Kind || out.Size.GetValue() != 7 || !out.At.Equal(in.At) ||
+		out.Addr != in.Addr || out.Book == nil || out.Book.Name != in.Book.Name || out.Path != in.Path {
+		t.Errorf("round trip mismatch:\n in %+v\nout %+v", in, out)
+	}
+
+	out = pathRequest{}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/shelves/1/14/1/2024-05-06T07:08:09Z/127.0.0.1/books/x/y", nil))
+	if out.Kind != descriptorpb.FieldDescriptorProto_TYPE_ENUM || out.Path != "y" {
+		t.Errorf("unexpected numeric enum binding %+v", out)
+	}
+
+	// WithRouter传入的engine不会被修改, 使用WithRawPath开启原始路径匹配, 转义后的 / 不会被当作分隔符
+	if New(WithRouter(gin.New())).GinEngine().UseRawPath {
+		t.Error("custom router should not be modified")
+	}
+	var book urlBook
+	s := New(WithRouter(gin.New()), WithRawPath())
+	s.GinEngine().GET("/files/:name", func(c *gin.Context) {
+		ctx := context.WithValue(context.Background(), "gin-ctx", c)
+		if err := BindVar(ctx, &book); err != nil {
+			t.Error(err)
+		}
+	})
+	w := httptest.NewRecorder()
+	s.GinEngine().ServeHTTP(w, httptest.NewRequest("GET", EncodeURL("/files/:name", &urlBook{Name: "a/b"}, false), nil))
+	if w.Code != 200 || book.Name != "a/b" {
+		t.Errorf("escaped param with custom router: %d %+v", w.Code, book)
+	}
+
+	// 递归引用自身的message
+	var n node
+	r.GET("/nodes/:name/:next.name/:next.next.name", func(c *gin.Context) {
+		ctx := context.WithValue(context.Background(), "gin-ctx", c)
+		if err := BindVar(ctx, &n); err != nil {
+			t.Error(err)
+		}
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/nodes/a/b/c", nil))
+	if n.Name != "a" || n.Child != nil || n.Next == nil || n.Next.Name != "b" || n.Next.Child != nil ||
+		n.Next.Next == nil || n.Next.Next.Name != "c" {
+		t.Errorf("unexpected recursive binding %+v", n)
+	}
+
+	tests := []struct {
+		path  string
+		param string
+	}{
+		{"/level/256", "level"},
+		{"/level/-1", "level"},
+		{"/kind/TYPE_NOTHING", "kind"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != 400 || !strings.Contains(w.Body.String(), `"parameter":"`+tt.param+`"`) ||
+			!strings.Contains(w.Body.String(), "invalid path parameter") {
+			t.Errorf("%s: unexpected response %d %s", tt.path, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/mangohow/mangokit/errors"
+)
+
+// ValidationReason 请求参数校验失败时错误的reason
+const ValidationReason = "VALIDATION_ERROR"
+
+// protoc-gen-validate生成的Validate/ValidateAll方法
+type validator interface {
+	Validate() error
+}
+
+type allValidator interface {
+	ValidateAll() error
+}
+
+// protoc-gen-validate生成的错误类型
+type fieldError interface {
+	Field() string
+	Reason() string
+	Cause() error
+}
+
+type multiError interface {
+	AllErrors() []error
+}
+
+// Validator 请求参数校验中间件
+// 如果请求参数实现了ValidateAll()或Validate()方法, 则在handler执行之前进行校验,
+// 校验失败返回errors.BadRequest, Metadata中以字段名为key记录每个字段的错误原因
+func Validator() Middleware {
+	return func(ctx context.Context, req interface{}, handler Handler) (interface{}, error) {
+		if err := validate(req); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func validate(req interface{}) error {
+	var err error
+	switch v := req.(type) {
+	case allValidator:
+		err = v.ValidateAll()
+	case validator:
+		err = v.Validate()
+	default:
+		return nil
+	}
+	if err == nil {
+		return nil
+	}
+
+	violations := make(map[string]string)
+	collectViolations("", err, violations)
+
+	return withMetadata(errors.BadRequestCause(http.StatusBadRequest, ValidationReason, err.Error(), err), violations)
+}
+
+// collectViolations 展开嵌套的校验错误, 嵌套message中的字段使用 a.b 的形式表示
+func collectViolations(prefix string, err error, violations map[string]string) {
+	switch e := err.(type) {
+	case multiError:
+		for _, fe := range e.AllErrors() {
+			collectViolations(prefix, fe, violations)
+		}
+	case fieldError:
+		field := e.Field()
+		if prefix != "" {
+			field = prefix + "." + field
+		}
+		if cause := e.Cause(); cause != nil {
+			if _, ok := cause.(fieldError); ok {
+				collectViolations(field, cause, violations)
+				return
+			}
+			if _, ok := cause.(multiError); ok {
+				collectViolations(field, cause, violations)
+				return
+			}
+		}
+		violations[field] = e.Reason()
+	}
+}
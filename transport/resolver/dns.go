@@ -0,0 +1,116 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type dnsResolver struct {
+	host     string
+	port     string
+	scheme   string
+	service  string
+	proto    string
+	srv      bool
+	interval time.Duration
+	resolver *net.Resolver
+}
+
+type DNSOption func(r *dnsResolver)
+
+// WithSRV 使用SRV记录解析实例, 实例的端口和权重来自SRV记录, 例如 WithSRV("http", "tcp")
+func WithSRV(service, proto string) DNSOption {
+	return func(r *dnsResolver) {
+		r.srv = true
+		r.service = service
+		r.proto = proto
+	}
+}
+
+// WithRefreshInterval 设置重新解析的间隔, 默认为30s
+func WithRefreshInterval(interval time.Duration) DNSOption {
+	return func(r *dnsResolver) {
+		r.interval = interval
+	}
+}
+
+// WithNetResolver 设置使用的net.Resolver, 默认为net.DefaultResolver
+func WithNetResolver(resolver *net.Resolver) DNSOption {
+	return func(r *dnsResolver) {
+		r.resolver = resolver
+	}
+}
+
+// NewDNS 通过DNS解析服务实例, target格式为 [scheme://]host[:port]
+// 默认解析A/AAAA记录, 使用target中的端口; 使用WithSRV时解析SRV记录
+func NewDNS(target string, opts ...DNSOption) (Resolver, error) {
+	r := &dnsResolver{
+		scheme:   "http",
+		interval: defaultRefreshInterval,
+		resolver: net.DefaultResolver,
+	}
+	if i := strings.Index(target, "://"); i != -1 {
+		r.scheme, target = target[:i], target[i+3:]
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	r.host = target
+	if !r.srv {
+		host, port, err := net.SplitHostPort(target)
+		if err != nil {
+			return nil, fmt.Errorf("dns resolver: invalid target %q, %v", target, err)
+		}
+		r.host, r.port = host, port
+	}
+
+	return r, nil
+}
+
+func (r *dnsResolver) Resolve(ctx context.Context) ([]Endpoint, error) {
+	if r.srv {
+		return r.resolveSRV(ctx)
+	}
+
+	addrs, err := r.resolver.LookupHost(ctx, r.host)
+	if err != nil {
+		return nil, err
+	}
+	endpoints := make([]Endpoint, 0, len(addrs))
+	for _, addr := range addrs {
+		endpoints = append(endpoints, Endpoint{
+			Addr:   r.scheme + "://" + net.JoinHostPort(addr, r.port),
+			Weight: 1,
+		})
+	}
+
+	return endpoints, nil
+}
+
+func (r *dnsResolver) resolveSRV(ctx context.Context) ([]Endpoint, error) {
+	_, srvs, err := r.resolver.LookupSRV(ctx, r.service, r.proto, r.host)
+	if err != nil {
+		return nil, err
+	}
+	endpoints := make([]Endpoint, 0, len(srvs))
+	for _, srv := range srvs {
+		endpoints = append(endpoints, Endpoint{
+			Addr:   r.scheme + "://" + net.JoinHostPort(strings.TrimSuffix(srv.Target, "."), strconv.Itoa(int(srv.Port))),
+			Weight: int(srv.Weight),
+			Metadata: map[string]string{
+				"priority": strconv.Itoa(int(srv.Priority)),
+			},
+		})
+	}
+
+	return endpoints, nil
+}
+
+func (r *dnsResolver) Watch(ctx context.Context, update func([]Endpoint)) {
+	poll(ctx, r, r.interval, update)
+}
@@ -0,0 +1,86 @@
+package resolver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type fileResolver struct {
+	path     string
+	interval time.Duration
+}
+
+type FileOption func(r *fileResolver)
+
+// WithFileInterval 设置检查文件变化的间隔, 默认为5s
+func WithFileInterval(interval time.Duration) FileOption {
+	return func(r *fileResolver) {
+		r.interval = interval
+	}
+}
+
+// NewFile 从文件中读取实例列表, 文件变化时自动更新
+// 文件每行一个实例, 格式为 addr [weight], 以#开头的行为注释, 例如
+//
+//	# user service
+//	http://10.0.0.1:8000 2
+//	10.0.0.2:8000
+//
+// 文件为空或者格式错误时保留之前的实例列表, 更新文件时建议先写入同目录下的临时文件再rename, 避免读取到写了一半的内容
+func NewFile(path string, opts ...FileOption) Resolver {
+	r := &fileResolver{
+		path:     path,
+		interval: 5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+func (r *fileResolver) Resolve(ctx context.Context) ([]Endpoint, error) {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseEndpoints(data)
+}
+
+func (r *fileResolver) Watch(ctx context.Context, update func([]Endpoint)) {
+	poll(ctx, r, r.interval, update)
+}
+
+func parseEndpoints(data []byte) ([]Endpoint, error) {
+	var endpoints []Endpoint
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("line %d: invalid endpoint %q", line, text)
+		}
+		ep := Endpoint{Addr: fields[0], Weight: 1}
+		if len(fields) == 2 {
+			weight, err := strconv.Atoi(fields[1])
+			if err != nil || weight <= 0 {
+				return nil, fmt.Errorf("line %d: invalid weight %q", line, fields[1])
+			}
+			ep.Weight = weight
+		}
+		endpoints = append(endpoints, ep)
+	}
+
+	return endpoints, scanner.Err()
+}
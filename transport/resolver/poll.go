@@ -0,0 +1,30 @@
+package resolver
+
+import (
+	"context"
+	"time"
+)
+
+const defaultRefreshInterval = 30 * time.Second
+
+// poll 每隔interval重新解析一次, 实例列表变化时调用update
+// 解析失败或者结果为空时保留之前的实例列表, 避免文件被截断后重新写入的过程中, 或者DNS短暂返回空结果时清空所有实例
+func poll(ctx context.Context, r Resolver, interval time.Duration, update func([]Endpoint)) {
+	last, _ := r.Resolve(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		endpoints, err := r.Resolve(ctx)
+		if err != nil || len(endpoints) == 0 || equal(last, endpoints) {
+			continue
+		}
+		last = endpoints
+		update(endpoints)
+	}
+}
@@ -0,0 +1,61 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Endpoint 服务实例地址
+type Endpoint struct {
+	// Addr 实例地址, 例如 http://127.0.0.1:8000 或 127.0.0.1:8000, 未指定scheme时使用http
+	Addr string
+	// Weight 实例权重, 用于加权负载均衡, 小于等于0时视为1
+	Weight int
+	// Metadata 实例元数据
+	Metadata map[string]string
+}
+
+// URL 返回带scheme的实例地址
+func (e Endpoint) URL() string {
+	if strings.Contains(e.Addr, "://") {
+		return strings.TrimSuffix(e.Addr, "/")
+	}
+	return "http://" + strings.TrimSuffix(e.Addr, "/")
+}
+
+func (e Endpoint) String() string {
+	return fmt.Sprintf("%s(weight=%d)", e.Addr, e.Weight)
+}
+
+// Resolver 服务发现, 解析出服务的实例列表
+type Resolver interface {
+	// Resolve 返回当前的实例列表
+	Resolve(ctx context.Context) ([]Endpoint, error)
+	// Watch 监听实例列表的变化, 变化时调用update, 阻塞直到ctx结束
+	Watch(ctx context.Context, update func([]Endpoint))
+}
+
+// equal 判断两个实例列表是否相同, 忽略顺序
+func equal(a, b []Endpoint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = sorted(a), sorted(b)
+	for i := range a {
+		if a[i].Addr != b[i].Addr || a[i].Weight != b[i].Weight {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sorted(endpoints []Endpoint) []Endpoint {
+	s := append([]Endpoint(nil), endpoints...)
+	sort.Slice(s, func(i, j int) bool {
+		return s[i].Addr < s[j].Addr
+	})
+	return s
+}
@@ -0,0 +1,100 @@
+package resolver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStatic(t *testing.T) {
+	endpoints, err := NewStatic("127.0.0.1:8000", "https://10.0.0.1/").Resolve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	urls := []string{endpoints[0].URL(), endpoints[1].URL()}
+	if !reflect.DeepEqual(urls, []string{"http://127.0.0.1:8000", "https://10.0.0.1"}) {
+		t.Errorf("unexpected urls %v", urls)
+	}
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "endpoints")
+	if err := os.WriteFile(path, []byte("# user service\nhttp://10.0.0.1:8000 2\n\n10.0.0.2:8000\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewFile(path, WithFileInterval(10*time.Millisecond))
+	endpoints, err := r.Resolve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []Endpoint{{Addr: "http://10.0.0.1:8000", Weight: 2}, {Addr: "10.0.0.2:8000", Weight: 1}}
+	if !reflect.DeepEqual(endpoints, expect) {
+		t.Fatalf("unexpected endpoints %v", endpoints)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates := make(chan []Endpoint, 16)
+	go r.Watch(ctx, func(endpoints []Endpoint) {
+		updates <- endpoints
+	})
+
+	// 写入临时文件后rename, 空文件和格式错误的文件不会清空实例列表
+	write := func(content string) {
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("")
+	write("10.0.0.1:8000 zero\n")
+	time.Sleep(30 * time.Millisecond)
+	write("10.0.0.3:8000 3\n")
+
+	expect = []Endpoint{{Addr: "10.0.0.3:8000", Weight: 3}}
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case endpoints = <-updates:
+			if len(endpoints) == 0 {
+				t.Fatal("endpoints cleared")
+			}
+		case <-timeout:
+			t.Fatal("file change not observed")
+		}
+		if reflect.DeepEqual(endpoints, expect) {
+			break
+		}
+	}
+
+	if _, err = parseEndpoints([]byte("10.0.0.1:8000 zero\n")); err == nil {
+		t.Error("expect invalid weight error")
+	}
+}
+
+func TestDNS(t *testing.T) {
+	if _, err := NewDNS("localhost"); err == nil {
+		t.Error("expect missing port error")
+	}
+
+	r, err := NewDNS("localhost:8000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoints, err := r.Resolve(context.Background())
+	if err != nil {
+		t.Skipf("lookup localhost: %v", err)
+	}
+	for _, ep := range endpoints {
+		if ep.URL() != "http://127.0.0.1:8000" && ep.URL() != "http://[::1]:8000" {
+			t.Errorf("unexpected endpoint %v", ep)
+		}
+	}
+}
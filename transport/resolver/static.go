@@ -0,0 +1,30 @@
+package resolver
+
+import "context"
+
+type static struct {
+	endpoints []Endpoint
+}
+
+// NewStatic 使用固定的地址列表, 例如 NewStatic("127.0.0.1:8000", "127.0.0.1:8001")
+func NewStatic(addrs ...string) Resolver {
+	endpoints := make([]Endpoint, 0, len(addrs))
+	for _, addr := range addrs {
+		endpoints = append(endpoints, Endpoint{Addr: addr, Weight: 1})
+	}
+
+	return &static{endpoints: endpoints}
+}
+
+// NewStaticEndpoints 使用固定的实例列表, 可以为每个实例设置权重和元数据
+func NewStaticEndpoints(endpoints ...Endpoint) Resolver {
+	return &static{endpoints: append([]Endpoint(nil), endpoints...)}
+}
+
+func (s *static) Resolve(ctx context.Context) ([]Endpoint, error) {
+	return s.endpoints, nil
+}
+
+func (s *static) Watch(ctx context.Context, update func([]Endpoint)) {
+	<-ctx.Done()
+}